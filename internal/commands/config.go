@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"github.com/idelchi/gonc/internal/config"
+)
+
+// unsettable are the flags a configuration file cannot set.
+var unsettable = map[string]bool{"help": true, "version": true, "show": true, "profile": true}
+
+// loadConfigFile merges the settings of the nearest project configuration file and of the selected profile
+// below flags and environment variables, and records where every setting came from for --show.
+// Only the settings that are flags of the running command are applied.
+func loadConfigFile(cfg *config.Config) func(*cobra.Command, []string) error {
+	return func(cmd *cobra.Command, _ []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("getting working directory: %w", err)
+		}
+
+		path, err := config.FindFile(cwd)
+		if err != nil {
+			return err
+		}
+
+		profile := viper.GetString("profile")
+
+		if path == "" {
+			if profile != "" {
+				return fmt.Errorf("%w: --profile %q requires a configuration file, one of %s",
+					config.ErrUsage, profile, strings.Join(config.FileNames, ", "))
+			}
+
+			cfg.Sources = sources(cmd, nil, nil, "", "")
+
+			return nil
+		}
+
+		file, err := config.LoadFile(path, cwd)
+		if err != nil {
+			return err
+		}
+
+		if err := checkSettings(cmd.Root(), file); err != nil {
+			return err
+		}
+
+		var selected map[string]any
+
+		if profile != "" {
+			var ok bool
+
+			if selected, ok = file.Profiles[profile]; !ok {
+				return fmt.Errorf("%w: unknown profile %q in %q", config.ErrUsage, profile, path)
+			}
+		}
+
+		applied := make(map[string]any)
+
+		for _, settings := range []map[string]any{file.Defaults, selected} {
+			for key, value := range settings {
+				if lookup(cmd, key) != nil {
+					applied[key] = value
+				}
+			}
+		}
+
+		if err := viper.MergeConfigMap(applied); err != nil {
+			return fmt.Errorf("merging configuration file: %w", err)
+		}
+
+		cfg.Sources = sources(cmd, file.Defaults, selected, path, profile)
+
+		return nil
+	}
+}
+
+// checkSettings rejects settings that are not a flag of any command, to catch typos.
+func checkSettings(root *cobra.Command, file *config.File) error {
+	known := make(map[string]bool)
+
+	var collect func(*cobra.Command)
+
+	collect = func(cmd *cobra.Command) {
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			known[flag.Name] = !unsettable[flag.Name]
+		})
+
+		for _, sub := range cmd.Commands() {
+			collect(sub)
+		}
+	}
+
+	collect(root)
+
+	check := func(settings map[string]any, where string) error {
+		for key := range settings {
+			if !known[key] {
+				return fmt.Errorf("%w: unknown setting %q in %s of %q", config.ErrUsage, key, where, file.Path)
+			}
+		}
+
+		return nil
+	}
+
+	if err := check(file.Defaults, "the defaults"); err != nil {
+		return err
+	}
+
+	for name, settings := range file.Profiles {
+		if err := check(settings, fmt.Sprintf("profile %q", name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// sources reports the origin of every setting of the running command that does not have its default value.
+func sources(cmd *cobra.Command, defaults, profile map[string]any, path, name string) map[string]string {
+	origins := make(map[string]string)
+
+	record := func(flag *pflag.Flag) {
+		if _, seen := origins[flag.Name]; seen || unsettable[flag.Name] && flag.Name != "profile" {
+			return
+		}
+
+		env := "GONC_" + strings.ToUpper(strings.ReplaceAll(flag.Name, "-", "_"))
+		_, inEnv := os.LookupEnv(env)
+		_, inProfile := profile[flag.Name]
+		_, inDefaults := defaults[flag.Name]
+
+		switch {
+		case flag.Changed:
+			origins[flag.Name] = "flag"
+		case inEnv:
+			origins[flag.Name] = "env " + env
+		case inProfile:
+			origins[flag.Name] = fmt.Sprintf("profile %q in %s", name, path)
+		case inDefaults:
+			origins[flag.Name] = path
+		}
+	}
+
+	cmd.Root().Flags().VisitAll(record)
+	cmd.Flags().VisitAll(record)
+
+	return origins
+}
+
+// lookup returns the flag of the running command or of the root command with the given name.
+func lookup(cmd *cobra.Command, name string) *pflag.Flag {
+	if flag := cmd.Flags().Lookup(name); flag != nil {
+		return flag
+	}
+
+	return cmd.Root().Flags().Lookup(name)
+}
@@ -9,7 +9,7 @@ import (
 
 // NewDecryptCommand creates a new cobra command for the decrypt subcommand.
 func NewDecryptCommand(cfg *config.Config) *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:     "decrypt [flags] [paths/patterns...]",
 		Aliases: []string{"dec"},
 		Short:   "Decrypt files",
@@ -19,8 +19,13 @@ func NewDecryptCommand(cfg *config.Config) *cobra.Command {
 
 			return preRun(cfg)(cmd, args)
 		},
-		RunE: func(_ *cobra.Command, _ []string) error {
-			return logic.Run(cfg)
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return logic.Run(cmd.Context(), cfg)
 		},
 	}
+
+	cmd.Flags().Bool("keep-ext", false, "Keep the encrypt suffix on files decrypted into --output-dir")
+	cmd.Flags().Bool("structured", false, "Decrypt the encrypted values inside JSON, YAML, TOML and .env files")
+
+	return cmd
 }
@@ -4,6 +4,12 @@
 //   - encryption
 //   - decryption
 //   - redaction
+//   - status reporting
+//   - reading and writing single structured values
+//   - packing files into a single encrypted archive
+//   - encrypting the entries of tar streams
+//   - synchronizing plaintext and encrypted trees
+//   - re-wrapping data keys under a new key-encryption key
 //
 // The package handles command-line parsing, configuration validation,
 // and environment variable binding through cobra and viper.
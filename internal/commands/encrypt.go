@@ -15,12 +15,14 @@ func NewEncryptCommand(cfg *config.Config) *cobra.Command {
 		Short:   "Encrypt files",
 		Args:    cobra.ArbitraryArgs,
 		PreRunE: preRun(cfg),
-		RunE: func(_ *cobra.Command, _ []string) error {
-			return logic.Run(cfg)
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return logic.Run(cmd.Context(), cfg)
 		},
 	}
 
 	cmd.Flags().BoolP("deterministic", "d", false, "Use deterministic encryption mode")
+	cmd.Flags().Bool("structured", false, "Encrypt only the values inside JSON, YAML, TOML and .env files")
+	cmd.Flags().String("encrypted-regex", "", "Only encrypt structured values whose path matches this regex")
 
 	return cmd
 }
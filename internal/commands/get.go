@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/idelchi/gogen/pkg/cobraext"
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/logic"
+)
+
+// NewGetCommand creates a new cobra command for the get subcommand.
+func NewGetCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get [flags] file path.to.key",
+		Short: "Print a single value of a structured file",
+		Long: `Print a single value of a JSON, YAML, TOML or .env file.
+Encrypted values are decrypted; the key is only required for those.
+
+Keys containing a dot are addressed by escaping it, as in 'a\.b'.`,
+		Args: cobra.ExactArgs(2), //nolint:mnd // file and path
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			cfg.Decrypt = true
+			cfg.Files = args[:1]
+
+			return cobraext.Validate(cfg, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			return logic.RunGet(cfg, args[1])
+		},
+	}
+}
@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/idelchi/gogen/pkg/cobraext"
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/logic"
+)
+
+// NewPackCommand creates a new cobra command for the pack subcommand.
+func NewPackCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "pack [flags] archive [paths/patterns...]",
+		Short: "Encrypt files into a single archive",
+		Long: `Stream the resolved files into a tar archive inside one encrypted envelope.
+Directories, modes, modification times and symlinks are preserved.`,
+		Args: cobra.MinimumNArgs(1),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			return preRun(cfg)(cmd, args[1:])
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			return logic.RunPack(cfg, args[0])
+		},
+	}
+
+	cmd.Flags().BoolP("deterministic", "d", false, "Use deterministic encryption mode")
+
+	return cmd
+}
+
+// NewUnpackCommand creates a new cobra command for the unpack subcommand.
+func NewUnpackCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unpack [flags] archive [destination]",
+		Short: "Extract an archive written by pack",
+		Long: `Authenticate an archive written by pack and extract it into the destination, "." by default.
+Entries that would be written outside the destination are rejected.
+--include and --exclude select which entries are extracted.`,
+		Args: cobra.RangeArgs(1, 2), //nolint:mnd // archive and optional destination
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			cfg.Files = args[:1]
+
+			return cobraext.Validate(cfg, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			dest := "."
+			if len(args) > 1 {
+				dest = args[1]
+			}
+
+			return logic.RunUnpack(cfg, args[0], dest)
+		},
+	}
+}
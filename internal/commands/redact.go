@@ -3,6 +3,7 @@ package commands
 import (
 	"github.com/spf13/cobra"
 
+	"github.com/idelchi/gogen/pkg/cobraext"
 	"github.com/idelchi/gonc/internal/config"
 	"github.com/idelchi/gonc/internal/logic"
 )
@@ -19,13 +20,44 @@ func NewRedactCommand(cfg *config.Config) *cobra.Command {
 
 			return preRun(cfg)(cmd, args)
 		},
-		RunE: func(_ *cobra.Command, _ []string) error {
-			return logic.RunRedact(cfg)
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return logic.RunRedact(cmd.Context(), cfg)
 		},
 	}
 
 	cmd.Flags().String("content", "<REDACTED>", "Replacement content for redacted files")
+	cmd.Flags().String("shape", "content",
+		"Placeholder shape: content, size (repeat content to the original length), random or lines")
+	cmd.Flags().String("content-rules", "", "Path to JSONC file mapping path patterns to content templates")
 	cmd.Flags().Bool("hash", false, "Append SHA-256 hash of the original file to the content")
+	cmd.PersistentFlags().String("hash-key", "", "Key for an HMAC-SHA-256 hash instead of plain SHA-256 (hex-encoded)")
+	cmd.PersistentFlags().String("hash-key-file", "", "Path to the key file for an HMAC-SHA-256 hash (hex-encoded)")
+	cmd.Flags().Bool("structured", false, "Keep the structure of JSON, YAML, TOML and .env files, redacting only values")
+	cmd.Flags().Bool("in-place-secrets", false, "Replace only detected secrets inside files with the content")
+	cmd.Flags().String("secret-rules", "", "Path to JSONC file with additional secret detection rules")
+	cmd.Flags().String("stash", "", "Directory to stash the originals in, encrypted with --key/--key-file")
+	cmd.Flags().Bool("no-detectors", false, "Disable the built-in secret detectors, using only --secret-rules")
+
+	cmd.AddCommand(NewVerifyCommand(cfg))
 
 	return cmd
 }
+
+// NewVerifyCommand creates a new cobra command for the redact verify subcommand.
+func NewVerifyCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify [flags] plaintext redacted",
+		Short: "Check that a plaintext file matches a hashed redaction placeholder",
+		Long: `Check that a plaintext file matches the hash in a placeholder written by redact --hash.
+Placeholders with a keyed hash require the same --hash-key or --hash-key-file.`,
+		Args: cobra.ExactArgs(2), //nolint:mnd // plaintext and redacted file
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			cfg.Files = args
+
+			return cobraext.Validate(cfg, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			return logic.RunVerify(cfg, args[0], args[1])
+		},
+	}
+}
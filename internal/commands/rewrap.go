@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/logic"
+)
+
+// NewRewrapCommand creates a new cobra command for the rewrap subcommand.
+func NewRewrapCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rewrap [flags] [paths/patterns...]",
+		Short: "Re-wrap data keys under a new key-encryption key",
+		Long: `Rotate the key-encryption key of files encrypted with --key-provider.
+The data key in every header is unwrapped with --from-provider and wrapped again with --key-provider;
+payloads are copied unchanged. Files already wrapped by --key-provider are left as they are.`,
+		Args: cobra.ArbitraryArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Rewrap = true
+
+			return preRun(cfg)(cmd, args)
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			return logic.RunRewrap(cmd.Context(), cfg)
+		},
+	}
+
+	cmd.Flags().String("from-provider", "", "Key provider currently wrapping the data keys: local:<kek-file> or exec:<command>")
+
+	return cmd
+}
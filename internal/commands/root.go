@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"fmt"
+	"os"
 	"runtime"
 
 	"github.com/spf13/cobra"
@@ -12,25 +14,39 @@ import (
 // NewRootCommand creates the root command with common configuration.
 // It sets up environment variable binding and flag handling.
 func NewRootCommand(cfg *config.Config, version string) *cobra.Command {
-	root := cobraext.NewDefaultRootCommand(version)
+	root := cobraext.NewDefaultRootCommand(version, loadConfigFile(cfg), warnKeyFlag)
 
 	root.Use = "gonc [flags] command [flags]"
 	root.Short = "File encryption utility"
 	root.Long = `A file encryption utility that supports deterministic and non-deterministic modes.
 Provides commands for key generation, encryption, and decryption.`
 
-	root.Flags().BoolP("show", "s", false, "Show the configuration and exit")
+	root.Flags().BoolP("show", "s", false, "Show the configuration and where each setting came from, and exit")
+	root.Flags().String("profile", "", "Profile of the .gonc.yaml configuration file to apply")
 	root.Flags().IntP("parallel", "j", runtime.NumCPU(), "Number of parallel workers, defaults to number of CPUs")
 	root.Flags().BoolP("quiet", "q", false, "Suppress non-error output")
 	root.Flags().Bool("delete", false, "Delete the original file after successful encryption/decryption")
+	root.Flags().Bool("force", false, "Replace existing outputs that differ from the new ones")
+	root.Flags().Bool("backup", false, "Move existing outputs that differ from the new ones to <output>.bak")
+	root.Flags().String("output", "text", "Output format for processed files: text, json or ndjson")
+	root.Flags().Bool("verify", false, "Decrypt and check every output against its input before keeping it or deleting the input")
+	root.Flags().String("durable", "auto", "Sync outputs to disk before continuing: auto (with --delete), on or off")
+	root.Flags().Bool("atomic-batch", false, "Move outputs into place only once every file succeeded, rolling back otherwise")
+	root.Flags().Bool("fail-fast", false, "Stop starting new files after the first error")
 
 	root.Flags().StringP("key", "k", "", "Encryption key (64 or 32 bytes, hex-encoded)")
 	root.Flags().
 		StringP("key-file", "f", "", "Path to the key file with the encryption key (64 or 32 bytes, hex-encoded)")
+	root.Flags().String("key-command", "", "Command printing the encryption key or a key file on its standard output")
+	root.Flags().Int("key-fd", -1, "Inherited file descriptor to read the encryption key or a key file from")
+	root.Flags().Bool("insecure-key-file", false, "Use key files that other users can access or that belong to another user")
+	root.Flags().String("key-provider", "", "Wrap a random per-file data key with a key provider: local:<kek-file> or exec:<command>")
 
 	root.Flags().String("encrypt-ext", ".enc", "Suffix to append to encrypted files")
 	root.Flags().String("decrypt-ext", "", "Suffix to append to decrypted files, after stripping the encrypted suffix")
 
+	root.Flags().String("output-dir", "", "Directory to mirror outputs into instead of writing them next to their inputs")
+
 	root.Flags().StringSlice("include", nil, "Glob patterns to narrow results (repeatable)")
 	root.Flags().StringSlice("exclude", nil, "Glob patterns to exclude from results (repeatable)")
 	root.Flags().String("include-from", "", "Path to JSONC file with include glob patterns")
@@ -38,9 +54,34 @@ Provides commands for key generation, encryption, and decryption.`
 
 	root.Flags().Bool("dry", false, "Show what would be processed without actually doing it")
 	root.Flags().Bool("stats", false, "Print processing statistics after completion")
+	root.Flags().Bool("progress", false, "Show live progress: bytes, files, throughput and ETA")
 	root.Flags().Bool("preserve-timestamps", false, "Preserve original file modification times")
 
-	root.AddCommand(NewEncryptCommand(cfg), NewDecryptCommand(cfg), NewRedactCommand(cfg), NewCheckCommand(cfg))
+	root.AddCommand(
+		NewEncryptCommand(cfg),
+		NewDecryptCommand(cfg),
+		NewRedactCommand(cfg),
+		NewUnredactCommand(cfg),
+		NewCheckCommand(cfg),
+		NewStatusCommand(cfg),
+		NewGetCommand(cfg),
+		NewSetCommand(cfg),
+		NewPackCommand(cfg),
+		NewUnpackCommand(cfg),
+		NewTarCommand(cfg),
+		NewSyncCommand(cfg),
+		NewRewrapCommand(cfg),
+	)
 
 	return root
 }
+
+// warnKeyFlag warns when the key was given with --key, as command lines are visible to other users.
+func warnKeyFlag(cmd *cobra.Command, _ []string) error {
+	if cmd.Root().Flags().Changed("key") {
+		fmt.Fprintln(os.Stderr,
+			"Warning: --key is visible to other users in the process list, prefer --key-file, --key-command or --key-fd")
+	}
+
+	return nil
+}
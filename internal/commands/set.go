@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/idelchi/gogen/pkg/cobraext"
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/logic"
+)
+
+// NewSetCommand creates a new cobra command for the set subcommand.
+func NewSetCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "set [flags] file path.to.key value",
+		Short: "Replace a single value of a structured file",
+		Long: `Replace a single value of a JSON, YAML, TOML or .env file in place.
+Encrypted values are re-encrypted with the mode they were encrypted with;
+the rest of the file is left unchanged.
+
+Keys containing a dot are addressed by escaping it, as in 'a\.b'.`,
+		Args: cobra.ExactArgs(3), //nolint:mnd // file, path and value
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			// Accept either key size, the mode is taken from the value being replaced.
+			cfg.Decrypt = true
+			cfg.Files = args[:1]
+
+			return cobraext.Validate(cfg, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			return logic.RunSet(cfg, args[1], args[2])
+		},
+	}
+}
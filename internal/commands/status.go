@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/logic"
+)
+
+// NewStatusCommand creates a new cobra command for the status subcommand.
+func NewStatusCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [flags] [paths/patterns...]",
+		Short: "Compare plaintext files with their encrypted counterparts",
+		Long: `Report, for every resolved file, whether its encrypted counterpart is up to date.
+Contents are compared when a key is given; otherwise modification times decide.
+Exits non-zero when any pair is out of sync.`,
+		Args: cobra.ArbitraryArgs,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			cfg.Status = true
+
+			return preRun(cfg)(cmd, args)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return logic.RunStatus(cfg)
+		},
+	}
+
+	cmd.Flags().Bool("json", false, "Print the status report as JSON")
+
+	return cmd
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/idelchi/gogen/pkg/cobraext"
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/logic"
+)
+
+// NewSyncCommand creates a new cobra command for the sync subcommand.
+func NewSyncCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync [flags] plain-dir encrypted-dir",
+		Short: "Synchronize a plaintext tree with an encrypted tree",
+		Long: `Encrypt, decrypt or delete files so that both trees hold the same contents.
+Changes are detected against the state of the last sync, kept in plain-dir/.gonc-sync.json.
+Files changed on both sides are reported as conflicts unless --prefer picks a side.`,
+		Args: cobra.ExactArgs(2), //nolint:mnd // plaintext and encrypted directory
+		PreRunE: func(_ *cobra.Command, args []string) error {
+			cfg.Files = args
+
+			return cobraext.Validate(cfg, cfg)
+		},
+		RunE: func(_ *cobra.Command, args []string) error {
+			return logic.RunSync(cfg, args[0], args[1])
+		},
+	}
+
+	cmd.Flags().BoolP("deterministic", "d", false, "Use deterministic encryption mode")
+	cmd.Flags().String("prefer", "", "Resolve conflicts in favor of one side: plain or encrypted")
+
+	return cmd
+}
@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/idelchi/gogen/pkg/cobraext"
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/logic"
+)
+
+// NewTarCommand creates a new cobra command for the tar subcommand.
+func NewTarCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tar command [flags]",
+		Short: "Encrypt or decrypt the entries of a tar stream",
+		Long: `Read a tar stream from stdin and write a tar stream to stdout,
+encrypting or decrypting the data of each selected regular entry.
+Directories and symlinks pass through unchanged; --include and --exclude select the entries.`,
+	}
+
+	cmd.AddCommand(NewTarEncryptCommand(cfg), NewTarDecryptCommand(cfg))
+
+	return cmd
+}
+
+// NewTarEncryptCommand creates a new cobra command for the tar encrypt subcommand.
+func NewTarEncryptCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "encrypt [flags]",
+		Aliases: []string{"enc"},
+		Short:   "Encrypt the entries of a tar stream",
+		Long:    "Encrypt each selected regular entry and append the encrypt suffix to its name.",
+		Args:    cobra.NoArgs,
+		PreRunE: func(_ *cobra.Command, _ []string) error {
+			return cobraext.Validate(cfg, cfg)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return logic.RunTar(cfg, os.Stdin, os.Stdout)
+		},
+	}
+
+	cmd.Flags().BoolP("deterministic", "d", false, "Use deterministic encryption mode")
+
+	return cmd
+}
+
+// NewTarDecryptCommand creates a new cobra command for the tar decrypt subcommand.
+func NewTarDecryptCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:     "decrypt [flags]",
+		Aliases: []string{"dec"},
+		Short:   "Decrypt the entries of a tar stream",
+		Long: `Decrypt each regular entry with the encrypt suffix, or each entry selected by --include,
+replacing the encrypt suffix with the decrypt suffix.
+Each entry is spooled to a temporary file and only written once it is authenticated.`,
+		Args: cobra.NoArgs,
+		PreRunE: func(_ *cobra.Command, _ []string) error {
+			cfg.Decrypt = true
+
+			return cobraext.Validate(cfg, cfg)
+		},
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return logic.RunTar(cfg, os.Stdin, os.Stdout)
+		},
+	}
+}
@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/logic"
+)
+
+// NewUnredactCommand creates a new cobra command for the unredact subcommand.
+func NewUnredactCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "unredact [flags] [paths...]",
+		Short: "Restore files redacted with a stash",
+		Long: `Restore the originals stashed by redact --stash, limited to the given paths.
+Every placeholder must be unchanged since redaction; otherwise nothing is restored.
+Restored entries are removed from the stash together with their placeholders.`,
+		Args:    cobra.ArbitraryArgs,
+		PreRunE: preRun(cfg),
+		RunE: func(_ *cobra.Command, _ []string) error {
+			return logic.RunUnredact(cfg)
+		},
+	}
+
+	cmd.Flags().String("stash", "", "Directory holding the stashed originals")
+
+	return cmd
+}
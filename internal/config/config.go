@@ -26,6 +26,32 @@ type Key struct {
 
 	// Key in a file
 	File string `label:"--key-file" mapstructure:"key-file" validate:"exclusive=String"`
+
+	// Command whose standard output holds the key
+	Command string `mapstructure:"key-command"`
+
+	// Inherited file descriptor to read the key from, negative when unset
+	FD int `mapstructure:"key-fd"`
+
+	// Provider wrapping per-file data keys: local:<path> or exec:<command>
+	Provider string `mapstructure:"key-provider"`
+
+	// Use key files that other users can access
+	Insecure bool `mapstructure:"insecure-key-file"`
+}
+
+// Provided reports whether any key source is configured.
+func (k Key) Provided() bool {
+	return k.String != "" || k.File != "" || k.Command != "" || k.FD >= 0 || k.Provider != ""
+}
+
+// HashKey contains the key for keyed redaction hashes.
+type HashKey struct {
+	// Key in hexadecimal format
+	String string `label:"--hash-key" mapstructure:"hash-key" mask:"fixed" validate:"omitempty,hexadecimal,exclusive=File"` //nolint:lll // struct tags
+
+	// Key in a file
+	File string `label:"--hash-key-file" mapstructure:"hash-key-file" validate:"exclusive=String"`
 }
 
 // Config contains the application configuration.
@@ -33,12 +59,36 @@ type Config struct {
 	// Show the configuration and exit
 	Show bool
 
+	// Profile of the configuration file to apply
+	Profile string `mapstructure:"profile"`
+
+	// Sources maps every setting that does not have its default value to where it came from
+	Sources map[string]string `mapstructure:"-"`
+
 	// Quiet mode
 	Quiet bool
 
 	// Delete the original file after successful encryption/decryption
 	Delete bool
 
+	// Replace existing outputs that differ from the new ones
+	Force bool `label:"--force" mapstructure:"force" validate:"exclusive=Backup"`
+
+	// Move existing outputs that differ from the new ones to a backup before replacing them
+	Backup bool `label:"--backup" mapstructure:"backup" validate:"exclusive=Force"`
+
+	// Read every output back and check it against the input before it is kept
+	Verify bool `mapstructure:"verify"`
+
+	// Flush outputs and their directories to stable storage: auto (with --delete), on or off
+	Durable string `mapstructure:"durable" validate:"omitempty,oneof=auto on off"`
+
+	// Move all outputs into place only once every file succeeded
+	AtomicBatch bool `mapstructure:"atomic-batch"`
+
+	// Stop starting new files after the first error
+	FailFast bool `mapstructure:"fail-fast"`
+
 	// Number of files to process in parallel
 	Parallel int
 
@@ -48,21 +98,72 @@ type Config struct {
 	// Suffixes for encrypted and decrypted files
 	Suffixes Suffixes `mapstructure:",squash"`
 
+	// Side that wins sync conflicts: plain or encrypted
+	Prefer string `mapstructure:"prefer" validate:"omitempty,oneof=plain encrypted"`
+
+	// Directory to mirror the outputs into instead of writing them next to their inputs
+	OutputDir string `mapstructure:"output-dir"`
+
+	// Keep the encrypt suffix on decrypted files written to the output directory
+	KeepExt bool `mapstructure:"keep-ext"`
+
 	// Encryption mode
 	Deterministic bool
 
 	// Decrypt files
 	Decrypt bool `mapstructure:"-"`
 
+	// Structured mode — process only the values inside JSON, YAML, TOML and .env files
+	Structured bool `mapstructure:"structured"`
+
+	// Regular expression selecting which structured value paths are encrypted
+	EncryptedRegex string `mapstructure:"encrypted-regex"`
+
 	// Redact mode — replace file contents with fixed string
 	Redact bool `mapstructure:"-"`
 
+	// Status mode — compare plaintext files with their encrypted counterparts
+	Status bool `mapstructure:"-"`
+
+	// Rewrap mode — re-wrap the data keys of envelopes under a new key-encryption key
+	Rewrap bool `mapstructure:"-"`
+
+	// Provider the data keys to re-wrap are currently wrapped by
+	FromProvider string `mapstructure:"from-provider"`
+
+	// Emit machine-readable JSON output
+	JSON bool `mapstructure:"json"`
+
+	// Output format for processed files: text, json or ndjson
+	Output string `mapstructure:"output" validate:"omitempty,oneof=text json ndjson"`
+
 	// Content string to write when redacting
 	Content string `mapstructure:"content"`
 
+	// Shape of the placeholder: content, size, random or lines
+	Shape string `mapstructure:"shape" validate:"omitempty,oneof=content size random lines"`
+
+	// Path to JSONC file mapping path patterns to content templates
+	ContentRules string `mapstructure:"content-rules"`
+
 	// Hash mode — append SHA-256 hash of the original file to the content
 	Hash bool `mapstructure:"hash"`
 
+	// HashKey switches the hash to HMAC-SHA-256 with the given key
+	HashKey HashKey `mapstructure:",squash"`
+
+	// Redact only detected secrets inside files instead of whole files
+	InPlaceSecrets bool `mapstructure:"in-place-secrets"`
+
+	// Path to JSONC file with secret detection rules
+	SecretRules string `mapstructure:"secret-rules"`
+
+	// Disable the built-in secret detectors, using only the loaded rules
+	NoDetectors bool `mapstructure:"no-detectors"`
+
+	// Directory to stash encrypted originals of redacted files in
+	Stash string `mapstructure:"stash"`
+
 	// Inline include glob patterns
 	Include []string `mapstructure:"include"`
 
@@ -81,6 +182,9 @@ type Config struct {
 	// Print processing statistics
 	Stats bool
 
+	// Show live progress on standard error
+	Progress bool `mapstructure:"progress"`
+
 	// Preserve original file modification times
 	PreserveTimestamps bool `mapstructure:"preserve-timestamps"`
 
@@ -93,6 +197,12 @@ func (c Config) Display() bool {
 	return c.Show
 }
 
+// Durability reports whether outputs are flushed to stable storage before inputs are removed.
+// Unless set explicitly, it is enabled together with --delete.
+func (c Config) Durability() bool {
+	return c.Durable == "on" || (c.Durable != "off" && c.Delete)
+}
+
 // Validate performs configuration validation using the validator package.
 // It returns a wrapped ErrUsage if any validation rules are violated.
 func (c Config) Validate(config any) error {
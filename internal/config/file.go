@@ -0,0 +1,194 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/tidwall/jsonc"
+)
+
+// FileNames are the names of the project configuration file, in the order they are looked for.
+var FileNames = []string{".gonc.yaml", ".gonc.yml", ".gonc.jsonc", ".gonc.json"}
+
+// pathSettings are the settings holding paths, which a configuration file resolves against its own directory.
+var pathSettings = map[string]bool{
+	"key-file":      true,
+	"hash-key-file": true,
+	"include-from":  true,
+	"exclude-from":  true,
+	"content-rules": true,
+	"secret-rules":  true,
+	"stash":         true,
+	"output-dir":    true,
+}
+
+// flagOnlySettings are the settings that run commands, read inherited file descriptors, weaken the key file check
+// or delete files. A configuration file may come from an untrusted checkout, so these must be given as flags or
+// environment variables.
+var flagOnlySettings = map[string]bool{
+	"key-command":       true,
+	"key-fd":            true,
+	"insecure-key-file": true,
+	"delete":            true,
+}
+
+// providerSettings are the settings holding key provider specs, whose exec providers run commands.
+var providerSettings = map[string]bool{
+	"key-provider":  true,
+	"from-provider": true,
+}
+
+// File is a project configuration file: defaults for every command and named profiles.
+// Settings are keyed by their flag names.
+type File struct {
+	// Path of the file
+	Path string
+
+	// Defaults apply to every run
+	Defaults map[string]any
+
+	// Profiles are selected with --profile and take precedence over the defaults
+	Profiles map[string]map[string]any
+}
+
+// FindFile walks up from dir and returns the first configuration file found, or "" if there is none.
+func FindFile(dir string) (string, error) {
+	for {
+		for _, name := range FileNames {
+			path := filepath.Join(dir, name)
+
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("looking for configuration file: %w", err)
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+
+		dir = parent
+	}
+}
+
+// LoadFile parses a YAML, JSON or JSONC configuration file.
+// Relative paths in it are made relative to cwd, so that they keep pointing next to the file.
+func LoadFile(path, cwd string) (*File, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading configuration file: %w", err)
+	}
+
+	var raw map[string]any
+
+	switch filepath.Ext(path) {
+	case ".json", ".jsonc":
+		err = json.Unmarshal(jsonc.ToJSON(data), &raw)
+	default:
+		err = yaml.Unmarshal(data, &raw)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: parsing %q: %w", ErrUsage, path, err)
+	}
+
+	file := &File{Path: path, Defaults: raw, Profiles: map[string]map[string]any{}}
+
+	if file.Defaults == nil {
+		file.Defaults = map[string]any{}
+	}
+
+	if profiles, ok := file.Defaults["profiles"]; ok {
+		delete(file.Defaults, "profiles")
+
+		named, ok := profiles.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q: profiles must be a mapping of names to settings", ErrUsage, path)
+		}
+
+		for name, settings := range named {
+			values, ok := settings.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: %q: profile %q must be a mapping of settings", ErrUsage, path, name)
+			}
+
+			file.Profiles[name] = values
+		}
+	}
+
+	dir := filepath.Dir(path)
+
+	for _, settings := range append([]map[string]any{file.Defaults}, mapsOf(file.Profiles)...) {
+		if err := checkFlagOnly(settings); err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrUsage, path, err)
+		}
+
+		if err := resolvePaths(settings, dir, cwd); err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrUsage, path, err)
+		}
+	}
+
+	return file, nil
+}
+
+// mapsOf returns the settings of all profiles.
+func mapsOf(profiles map[string]map[string]any) []map[string]any {
+	settings := make([]map[string]any, 0, len(profiles))
+
+	for _, values := range profiles {
+		settings = append(settings, values)
+	}
+
+	return settings
+}
+
+// checkFlagOnly rejects settings that would let the file run commands, weaken checks or delete files.
+func checkFlagOnly(settings map[string]any) error {
+	for key, value := range settings {
+		if flagOnlySettings[key] {
+			return fmt.Errorf("%s cannot be set in a configuration file, pass it as a flag or environment variable", key)
+		}
+
+		if spec, ok := value.(string); ok && providerSettings[key] && strings.HasPrefix(spec, "exec:") {
+			return fmt.Errorf("%s cannot name an exec provider in a configuration file, "+
+				"pass it as a flag or environment variable", key)
+		}
+	}
+
+	return nil
+}
+
+// resolvePaths rewrites the relative path settings from dir to cwd.
+func resolvePaths(settings map[string]any, dir, cwd string) error {
+	for key, value := range settings {
+		if !pathSettings[key] {
+			continue
+		}
+
+		path, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s must be a string", key)
+		}
+
+		if path == "" || filepath.IsAbs(path) {
+			continue
+		}
+
+		rel, err := filepath.Rel(cwd, filepath.Join(dir, path))
+		if err != nil {
+			return fmt.Errorf("resolving %s: %w", key, err)
+		}
+
+		settings[key] = rel
+	}
+
+	return nil
+}
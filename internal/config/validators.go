@@ -56,5 +56,9 @@ func validateExclusive(fl validator.FieldLevel) bool {
 		return currentValue == "" || otherValue == ""
 	}
 
+	if field.Kind() == reflect.Bool && otherField.Kind() == reflect.Bool {
+		return !field.Bool() || !otherField.Bool()
+	}
+
 	return true
 }
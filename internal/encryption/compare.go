@@ -0,0 +1,147 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/idelchi/gonc/internal/config"
+)
+
+// errMismatch signals that re-encrypted plaintext diverged from the stored ciphertext.
+var errMismatch = errors.New("ciphertext mismatch")
+
+// Matches reports whether the ciphertext file holds exactly the content of the plaintext file.
+// Deterministic envelopes are compared byte for byte against a fresh encryption of the plaintext.
+// Randomized envelopes are decrypted and authenticated, and the hashes of both plaintexts are compared.
+// The executable bit is part of the comparison, as re-encrypting would change it.
+func (p *Processor) Matches(plainPath, cipherPath string) (bool, error) {
+	plainInfo, err := os.Stat(plainPath)
+	if err != nil {
+		return false, fmt.Errorf("getting file info for %q: %w", plainPath, err)
+	}
+
+	cipherFile, err := os.Open(filepath.Clean(cipherPath))
+	if err != nil {
+		return false, fmt.Errorf("opening ciphertext: %w", err)
+	}
+	defer cipherFile.Close()
+
+	reader := bufio.NewReader(cipherFile)
+
+	header, info, err := readEnvelopeHeader(reader)
+	if err != nil {
+		return false, err
+	}
+
+	if info.executable != (plainInfo.Mode()&0o111 != 0) {
+		return false, nil
+	}
+
+	plainFile, err := os.Open(filepath.Clean(plainPath))
+	if err != nil {
+		return false, fmt.Errorf("opening plaintext: %w", err)
+	}
+	defer plainFile.Close()
+
+	switch info.mode {
+	case modeDeterministic:
+		return p.matchesDeterministic(plainFile, reader, header)
+	case modeRandomized:
+		key, err := p.keyFor(info)
+		if err != nil {
+			return false, err
+		}
+
+		defer key.destroy()
+
+		return p.matchesRandomized(plainFile, reader, header, key.key)
+	default:
+		return false, errors.New("unknown encryption mode")
+	}
+}
+
+// matchesDeterministic re-encrypts the plaintext and compares it against the remaining ciphertext.
+func (p *Processor) matchesDeterministic(plain io.Reader, cipher *bufio.Reader, header []byte) (bool, error) {
+	if err := p.initDeterministic(); err != nil {
+		return false, err
+	}
+
+	comparer := &compareWriter{reader: cipher}
+
+	streamingWriter := newStreamingWriter(comparer, p.daead, header)
+
+	if _, err := io.Copy(streamingWriter, plain); err != nil {
+		if errors.Is(err, errMismatch) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("re-encrypting plaintext: %w", err)
+	}
+
+	if err := streamingWriter.Close(); err != nil {
+		if errors.Is(err, errMismatch) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("re-encrypting plaintext: %w", err)
+	}
+
+	// Any trailing ciphertext means the stored file is longer than the plaintext.
+	if _, err := cipher.ReadByte(); !errors.Is(err, io.EOF) {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// matchesRandomized decrypts and authenticates the envelope and compares the hashes of both plaintexts.
+func (p *Processor) matchesRandomized(plain io.Reader, cipher io.Reader, header, key []byte) (bool, error) {
+	if len(key) != AesKeySize {
+		return false, fmt.Errorf("%w: decrypt: randomized data requires 32-byte key (64 hex characters)", config.ErrUsage)
+	}
+
+	hasher := sha256.New()
+
+	if err := p.decryptRandomized(cipher, hasher, header, key); err != nil {
+		return false, err
+	}
+
+	plainHasher := sha256.New()
+
+	if _, err := io.Copy(plainHasher, plain); err != nil {
+		return false, fmt.Errorf("hashing plaintext: %w", err)
+	}
+
+	return bytes.Equal(hasher.Sum(nil), plainHasher.Sum(nil)), nil
+}
+
+// compareWriter checks written bytes against a reader, failing on the first difference.
+type compareWriter struct {
+	reader io.Reader
+	buf    []byte
+}
+
+// Write implements io.Writer, returning errMismatch when data differs from the reader.
+func (cw *compareWriter) Write(data []byte) (int, error) {
+	if cap(cw.buf) < len(data) {
+		cw.buf = make([]byte, len(data))
+	}
+
+	buf := cw.buf[:len(data)]
+
+	if _, err := io.ReadFull(cw.reader, buf); err != nil {
+		return 0, errMismatch
+	}
+
+	if !bytes.Equal(buf, data) {
+		return 0, errMismatch
+	}
+
+	return len(data), nil
+}
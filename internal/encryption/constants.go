@@ -1,5 +1,6 @@
 package encryption
 
 const (
-	chunkSize = 1024 * 1024 // 1MB chunk size for deterministic encryption
+	chunkSize     = 1024 * 1024 // 1MB chunk size for deterministic encryption
+	chunkOverhead = 4 + 16      // length prefix and AES-SIV tag of each deterministic chunk
 )
@@ -79,7 +79,7 @@ func (p *Processor) decryptDeterministic(reader io.Reader, writer io.Writer, hea
 
 		decrypted, err := p.daead.DecryptDeterministically(encrypted, ad)
 		if err != nil {
-			return fmt.Errorf("decrypting chunk: %w", err)
+			return fmt.Errorf("%w: decrypting chunk %d: %w", ErrProcessing, chunkIndex-1, ErrAuthentication)
 		}
 
 		// Write decrypted chunk
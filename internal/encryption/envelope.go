@@ -2,20 +2,32 @@ package encryption
 
 import (
 	"bytes"
+	"crypto/aes"
+	"crypto/hmac"
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 
 	"golang.org/x/crypto/hkdf"
+
+	"github.com/idelchi/gonc/internal/keymem"
 )
 
 const (
 	envelopeMagic   = "GONC"
-	envelopeVersion = byte(1)
 	envelopeTagSize = sha256.Size
 
-	envelopeFlagExec = 0x01
+	// envelopeVersion is written for envelopes without optional sections, readable by every gonc release.
+	envelopeVersion = byte(1)
+	// envelopeVersionWrapped is written for envelopes with a wrapped data key,
+	// so that releases without support for it refuse them instead of misreading the section as the IV.
+	envelopeVersionWrapped = byte(2)
+
+	envelopeFlagExec    = 0x01
+	envelopeFlagWrapped = 0x04
 )
 
 type envelopeMode byte
@@ -25,12 +37,112 @@ const (
 	modeRandomized    envelopeMode = 0x02
 )
 
+// String returns the name of the mode.
+func (m envelopeMode) String() string {
+	switch m {
+	case modeDeterministic:
+		return "deterministic"
+	case modeRandomized:
+		return "randomized"
+	default:
+		return fmt.Sprintf("unknown (%d)", byte(m))
+	}
+}
+
 const envelopeHeaderSize = len(envelopeMagic) + 3
 
 // ErrProcessing indicates an error during envelope processing.
 var ErrProcessing = errors.New("envelope processing error")
 
-func newEnvelopeHeader(mode envelopeMode, executable bool) []byte {
+// ErrAuthentication indicates that an envelope or value did not authenticate, due to a wrong key or tampering.
+// It is always reported together with ErrProcessing.
+var ErrAuthentication = errors.New("authentication failed")
+
+// envelopeInfo describes a parsed envelope header.
+type envelopeInfo struct {
+	// mode is the encryption mode of the payload
+	mode envelopeMode
+
+	// executable reports whether the original file was executable
+	executable bool
+
+	// wrapped is the wrapped data key, if the payload is encrypted with one
+	wrapped *wrappedKey
+}
+
+// wrappedKey is a per-file data key wrapped by a key provider.
+// It is stored after the fixed fields, and is not part of the authenticated header:
+// tampering with it yields a wrong data key, which fails authentication, and rotating the
+// key-encryption key only rewrites this section.
+type wrappedKey struct {
+	// provider identifies the provider and key-encryption key that wrapped the data key
+	provider string
+
+	// key is the wrapped data key
+	key []byte
+}
+
+// marshal encodes the section: the provider ID prefixed by its 1-byte length,
+// then the wrapped key prefixed by its 2-byte big-endian length.
+func (w *wrappedKey) marshal() ([]byte, error) {
+	if len(w.provider) == 0 || len(w.provider) > math.MaxUint8 {
+		return nil, fmt.Errorf("provider ID must be 1 to %d bytes", math.MaxUint8)
+	}
+
+	if len(w.key) == 0 || len(w.key) > math.MaxUint16 {
+		return nil, fmt.Errorf("wrapped key must be 1 to %d bytes", math.MaxUint16)
+	}
+
+	section := make([]byte, 0, 1+len(w.provider)+2+len(w.key)) //nolint:mnd // length prefixes
+	section = append(section, byte(len(w.provider)))
+	section = append(section, w.provider...)
+	section = binary.BigEndian.AppendUint16(section, uint16(len(w.key)))
+
+	return append(section, w.key...), nil
+}
+
+// readWrappedKey reads a section written by marshal.
+func readWrappedKey(reader io.Reader) (*wrappedKey, error) {
+	var size [2]byte
+
+	if _, err := io.ReadFull(reader, size[:1]); err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	provider := make([]byte, size[0])
+	if _, err := io.ReadFull(reader, provider); err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	if _, err := io.ReadFull(reader, size[:]); err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	key := make([]byte, binary.BigEndian.Uint16(size[:]))
+	if _, err := io.ReadFull(reader, key); err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	return &wrappedKey{provider: string(provider), key: key}, nil
+}
+
+// envelopeBytes returns the header as written: the authenticated fixed fields, then the wrapped key section if any.
+func envelopeBytes(header []byte, wrapped *wrappedKey) ([]byte, error) {
+	if wrapped == nil {
+		return header, nil
+	}
+
+	section, err := wrapped.marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(append(make([]byte, 0, len(header)+len(section)), header...), section...), nil
+}
+
+// newEnvelopeHeader builds the authenticated header for the given mode.
+// With wrapped, the header announces a wrapped data key section.
+func newEnvelopeHeader(mode envelopeMode, executable, wrapped bool) []byte {
 	header := make([]byte, envelopeHeaderSize)
 	copy(header, []byte(envelopeMagic))
 
@@ -42,6 +154,11 @@ func newEnvelopeHeader(mode envelopeMode, executable bool) []byte {
 		flags |= envelopeFlagExec
 	}
 
+	if wrapped {
+		flags |= envelopeFlagWrapped
+		header[len(envelopeMagic)] = envelopeVersionWrapped
+	}
+
 	header[len(envelopeMagic)+1] = flags
 	header[len(envelopeMagic)+2] = byte(mode)
 
@@ -57,12 +174,23 @@ func parseEnvelopeHeader(header []byte) (envelopeMode, bool, error) {
 		return 0, false, fmt.Errorf("%w: invalid envelope magic", ErrProcessing)
 	}
 
+	// Every version only knows its own flags, as unknown ones may announce sections it cannot skip.
+	known := map[byte]byte{
+		envelopeVersion:        envelopeFlagExec,
+		envelopeVersionWrapped: envelopeFlagExec | envelopeFlagWrapped,
+	}
+
 	version := header[len(envelopeMagic)]
-	if version != envelopeVersion {
+
+	allowed, ok := known[version]
+	if !ok {
 		return 0, false, fmt.Errorf("%w: unsupported envelope version %d", ErrProcessing, version)
 	}
 
 	flags := header[len(envelopeMagic)+1]
+	if flags&^allowed != 0 {
+		return 0, false, fmt.Errorf("%w: unsupported flags 0x%02x in envelope version %d", ErrProcessing, flags, version)
+	}
 	mode := envelopeMode(header[len(envelopeMagic)+2])
 
 	switch mode {
@@ -76,6 +204,61 @@ func parseEnvelopeHeader(header []byte) (envelopeMode, bool, error) {
 	return mode, executable, nil
 }
 
+// readEnvelopeHeader reads the fixed header and any optional sections announced by its flags.
+// The returned header contains the authenticated fixed fields, without the wrapped key.
+func readEnvelopeHeader(reader io.Reader) ([]byte, envelopeInfo, error) {
+	header := make([]byte, envelopeHeaderSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, envelopeInfo{}, fmt.Errorf("reading header: %w", err)
+	}
+
+	mode, executable, err := parseEnvelopeHeader(header)
+	if err != nil {
+		return nil, envelopeInfo{}, err
+	}
+
+	info := envelopeInfo{mode: mode, executable: executable}
+
+	if header[len(envelopeMagic)+1]&envelopeFlagWrapped != 0 {
+		if mode != modeRandomized {
+			return nil, envelopeInfo{}, fmt.Errorf("%w: wrapped data key in %s envelope", ErrProcessing, mode)
+		}
+
+		if info.wrapped, err = readWrappedKey(reader); err != nil {
+			return nil, envelopeInfo{}, fmt.Errorf("%w: reading wrapped data key: %w", ErrProcessing, err)
+		}
+	}
+
+	return header, info, nil
+}
+
+// payloadSize returns the size of the payload that encrypts size bytes of plaintext in mode.
+func payloadSize(mode envelopeMode, size int64) int64 {
+	if mode == modeDeterministic {
+		chunks := (size + chunkSize - 1) / chunkSize
+
+		return size + chunks*chunkOverhead
+	}
+
+	return aes.BlockSize + size + envelopeTagSize
+}
+
+// plaintextSize returns the size of the plaintext encrypted in a payload of the given size.
+func plaintextSize(mode envelopeMode, payload int64) (int64, error) {
+	size := payload - aes.BlockSize - envelopeTagSize
+
+	if mode == modeDeterministic {
+		chunks := (payload + chunkSize + chunkOverhead - 1) / (chunkSize + chunkOverhead)
+		size = payload - chunks*chunkOverhead
+	}
+
+	if size < 0 {
+		return 0, fmt.Errorf("%w: payload too short", ErrProcessing)
+	}
+
+	return size, nil
+}
+
 func deriveRandomizedKeys(key []byte) ([]byte, []byte, error) {
 	const (
 		hkdfOutputLen       = 64
@@ -92,3 +275,23 @@ func deriveRandomizedKeys(key []byte) ([]byte, []byte, error) {
 
 	return derived[:randomizedEncKeyLen], derived[randomizedEncKeyLen:], nil
 }
+
+// plaintextDigest computes a keyed digest of a plaintext.
+// Keying the digest prevents recovering low-entropy plaintexts from it by brute force.
+func plaintextDigest(key []byte, reader io.Reader) ([]byte, error) {
+	digestKey := make([]byte, sha256.Size)
+
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte("gonc/digest")), digestKey); err != nil {
+		return nil, fmt.Errorf("deriving digest key: %w", err)
+	}
+
+	defer keymem.Wipe(digestKey)
+
+	mac := hmac.New(sha256.New, digestKey)
+
+	if _, err := io.Copy(mac, reader); err != nil {
+		return nil, fmt.Errorf("hashing plaintext: %w", err)
+	}
+
+	return mac.Sum(nil), nil
+}
@@ -0,0 +1,268 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/keymem"
+)
+
+// KeyFileType identifies a self-describing key file.
+const KeyFileType = "gonc-key"
+
+// maxKeySource limits how much is read from a key file descriptor.
+const maxKeySource = 64 << 10
+
+// errEmptyKey signals a key command or file descriptor that yielded nothing.
+var errEmptyKey = errors.New("no key received")
+
+// keyFile is a self-describing key file.
+type keyFile struct {
+	// Type is always KeyFileType
+	Type string `yaml:"type"`
+
+	// Key in hexadecimal format
+	Key string `yaml:"key"`
+
+	// Mode the key is meant for: randomized or deterministic, optional
+	Mode string `yaml:"mode"`
+}
+
+// fetched caches the keys read during the run by source, in locked memory. Commands and file descriptors
+// can only be read once, and every processor of the run shares the same copy until WipeKeys.
+var fetched = struct {
+	sync.Mutex
+
+	keys map[string]*keymem.Buffer
+}{keys: map[string]*keymem.Buffer{}}
+
+// loadKey reads the key from the single configured source, or returns nil when there is none.
+// The returned key is owned by the cache and must not be modified.
+func loadKey(cfg config.Key) ([]byte, error) {
+	sources := 0
+
+	for _, set := range []bool{cfg.String != "", cfg.File != "", cfg.Command != "", cfg.FD >= 0} {
+		if set {
+			sources++
+		}
+	}
+
+	if sources > 1 {
+		return nil, fmt.Errorf("%w: only one of --key, --key-file, --key-command and --key-fd can be used", config.ErrUsage)
+	}
+
+	switch {
+	case cfg.String != "":
+		return fetchString("key", cfg.String)
+	case cfg.File != "":
+		return readKeyFile(cfg.File, cfg.Insecure)
+	case cfg.Command != "":
+		return fetch("command:"+cfg.Command, func() ([]byte, error) { return runKeyCommand(cfg.Command) })
+	case cfg.FD >= 0:
+		return fetch("fd:"+strconv.Itoa(cfg.FD), func() ([]byte, error) { return readKeyFD(cfg.FD) })
+	default:
+		return nil, nil
+	}
+}
+
+// LoadHashKey reads the key for keyed redaction hashes, or returns nil when none is configured.
+// It is read like the encryption key: key files must only be accessible by their owner unless insecure is set,
+// and the key is held in locked memory until WipeKeys. The returned key must not be modified.
+func LoadHashKey(cfg config.HashKey, insecure bool) ([]byte, error) {
+	switch {
+	case cfg.String != "":
+		return fetchString("hash-key", cfg.String)
+	case cfg.File != "":
+		return readKeyFile(cfg.File, insecure)
+	default:
+		return nil, nil
+	}
+}
+
+// WipeKeys wipes and releases every key read during the run.
+// Processors and providers must not be used afterwards.
+func WipeKeys() {
+	fetched.Lock()
+	defer fetched.Unlock()
+
+	for _, buffer := range fetched.keys {
+		buffer.Destroy()
+	}
+
+	clear(fetched.keys)
+}
+
+// fetch returns the cached key for source, reading and parsing it on first use.
+// The data read is wiped once it is parsed.
+func fetch(source string, read func() ([]byte, error)) ([]byte, error) {
+	fetched.Lock()
+	defer fetched.Unlock()
+
+	if cached, ok := fetched.keys[source]; ok {
+		return cached.Bytes(), nil
+	}
+
+	data, err := read()
+	if err != nil {
+		return nil, err
+	}
+
+	defer keymem.Wipe(data)
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, errEmptyKey
+	}
+
+	parsed, err := parseKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	fetched.keys[source] = parsed
+
+	return parsed.Bytes(), nil
+}
+
+// fetchString returns the cached key given as a string, such as a flag value.
+// Only the parsed copy is kept in locked memory: Go strings are immutable, so the string itself, which the flag
+// and environment handling also hold, cannot be wiped.
+func fetchString(source, text string) ([]byte, error) {
+	return fetch(source, func() ([]byte, error) { return []byte(text), nil })
+}
+
+// readKeyFile reads a key file, refusing files that other users can access unless insecure is set.
+func readKeyFile(path string, insecure bool) ([]byte, error) {
+	return fetch("file:"+path, func() ([]byte, error) {
+		file, err := os.Open(filepath.Clean(path))
+		if err != nil {
+			return nil, fmt.Errorf("reading key file: %w", err)
+		}
+		defer file.Close()
+
+		// The opened file is checked, so that it cannot be swapped after the check.
+		info, err := file.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("reading key file: %w", err)
+		}
+
+		if !insecure {
+			if err := checkKeyFile(path, info); err != nil {
+				return nil, err
+			}
+		}
+
+		data, err := io.ReadAll(io.LimitReader(file, maxKeySource))
+		if err != nil {
+			return nil, fmt.Errorf("reading key file: %w", err)
+		}
+
+		return data, nil
+	})
+}
+
+// parseKey decodes a hex key or a self-describing key file into locked memory.
+func parseKey(data []byte) (*keymem.Buffer, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	if !bytes.Contains(trimmed, []byte(KeyFileType)) {
+		return decodeHex(trimmed)
+	}
+
+	var file keyFile
+
+	if err := yaml.Unmarshal(trimmed, &file); err != nil {
+		return nil, fmt.Errorf("%w: parsing key file: %w", config.ErrUsage, err)
+	}
+
+	if file.Type != KeyFileType {
+		return nil, fmt.Errorf("%w: unsupported key file type %q", config.ErrUsage, file.Type)
+	}
+
+	text := []byte(file.Key)
+	defer keymem.Wipe(text)
+
+	decoded, err := decodeHex(bytes.TrimSpace(text))
+	if err != nil {
+		return nil, fmt.Errorf("decoding key file: %w", err)
+	}
+
+	size := len(decoded.Bytes())
+	want := map[string]int{"": size, "randomized": AesKeySize, "deterministic": AesSivKeySize}
+
+	if expected, ok := want[file.Mode]; !ok || expected != size {
+		decoded.Destroy()
+
+		return nil, fmt.Errorf("%w: key file: %d-byte key does not fit mode %q", config.ErrUsage, size, file.Mode)
+	}
+
+	return decoded, nil
+}
+
+// decodeHex decodes a hex-encoded key into locked memory.
+func decodeHex(text []byte) (*keymem.Buffer, error) {
+	decoded := keymem.New(hex.DecodedLen(len(text)))
+
+	if _, err := hex.Decode(decoded.Bytes(), text); err != nil {
+		decoded.Destroy()
+
+		return nil, fmt.Errorf("%w: invalid hex key: %w", config.ErrUsage, err)
+	}
+
+	return decoded, nil
+}
+
+// runKeyCommand runs command through the shell and returns its standard output.
+// Standard input and error are inherited, so that the command can prompt for a passphrase.
+func runKeyCommand(command string) ([]byte, error) {
+	var stdout bytes.Buffer
+
+	cmd := shellCommand(command)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("running --key-command: %w", err)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+// shellCommand prepares command to run through the platform shell.
+func shellCommand(command string) *exec.Cmd {
+	shell, flag := "sh", "-c"
+
+	if runtime.GOOS == "windows" {
+		shell, flag = "cmd", "/C"
+	}
+
+	return exec.Command(shell, flag, command) //nolint:gosec,noctx // running the configured command is the point
+}
+
+// readKeyFD reads the key from an inherited file descriptor and closes it.
+func readKeyFD(fd int) ([]byte, error) {
+	file := os.NewFile(uintptr(fd), "key-fd")
+	if file == nil {
+		return nil, fmt.Errorf("%w: --key-fd %d is not a valid file descriptor", config.ErrUsage, fd)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxKeySource))
+	if err != nil {
+		return nil, fmt.Errorf("reading --key-fd %d: %w", fd, err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package encryption
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/idelchi/gonc/internal/config"
+)
+
+// checkKeyFile refuses a key file that other users can access or that belongs to another user.
+func checkKeyFile(path string, info os.FileInfo) error {
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf("%w: key file %q is accessible by group or others (mode %04o), "+
+			"restrict it with chmod 600 or pass --insecure-key-file", config.ErrUsage, path, perm)
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != os.Getuid() {
+		return fmt.Errorf("%w: key file %q is owned by uid %d, not by the current user (uid %d), "+
+			"pass --insecure-key-file to use it anyway", config.ErrUsage, path, stat.Uid, os.Getuid())
+	}
+
+	return nil
+}
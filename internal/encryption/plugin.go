@@ -0,0 +1,122 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/idelchi/gonc/internal/keymem"
+)
+
+// pluginVersion is the version of the exec-plugin protocol.
+const pluginVersion = 1
+
+// pluginRequest is written as JSON to the standard input of a key provider plugin.
+type pluginRequest struct {
+	// Version of the protocol
+	Version int `json:"version"`
+
+	// Operation is "wrap" or "unwrap"
+	Operation string `json:"operation"`
+
+	// Plaintext is the data key to wrap, base64-encoded
+	Plaintext []byte `json:"plaintext,omitempty"`
+
+	// Ciphertext is the wrapped data key to unwrap, base64-encoded
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+
+	// KeyID is the key-encryption key the data key was wrapped with, set for unwrap
+	KeyID string `json:"key_id,omitempty"`
+}
+
+// pluginResponse is read as JSON from the standard output of a key provider plugin.
+type pluginResponse struct {
+	// Plaintext is the unwrapped data key, base64-encoded
+	Plaintext []byte `json:"plaintext,omitempty"`
+
+	// Ciphertext is the wrapped data key, base64-encoded
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+
+	// KeyID is the key-encryption key used by wrap
+	KeyID string `json:"key_id,omitempty"`
+
+	// Error reports a failed operation
+	Error string `json:"error,omitempty"`
+}
+
+// errPlugin signals a key provider plugin that failed or answered incorrectly.
+var errPlugin = errors.New("key provider plugin failed")
+
+// execProvider runs a plugin command for every operation, exchanging one JSON object each way.
+type execProvider struct {
+	// command is run through the shell
+	command string
+}
+
+// Wrap implements Provider.
+func (e *execProvider) Wrap(dataKey []byte) ([]byte, string, error) {
+	response, err := e.call(pluginRequest{Operation: "wrap", Plaintext: dataKey})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(response.Ciphertext) == 0 || response.KeyID == "" {
+		return nil, "", fmt.Errorf("%w: wrap must return ciphertext and key_id", errPlugin)
+	}
+
+	return response.Ciphertext, response.KeyID, nil
+}
+
+// Unwrap implements Provider.
+func (e *execProvider) Unwrap(wrapped []byte, id string) ([]byte, error) {
+	response, err := e.call(pluginRequest{Operation: "unwrap", Ciphertext: wrapped, KeyID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Plaintext) == 0 {
+		return nil, fmt.Errorf("%w: unwrap must return plaintext", errPlugin)
+	}
+
+	return response.Plaintext, nil
+}
+
+// call runs the plugin with a request and decodes its response.
+func (e *execProvider) call(request pluginRequest) (*pluginResponse, error) {
+	request.Version = pluginVersion
+
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("encoding plugin request: %w", err)
+	}
+
+	// Requests and responses carry data keys in the clear.
+	defer keymem.Wipe(input)
+
+	var stdout bytes.Buffer
+
+	defer func() { keymem.Wipe(stdout.Bytes()) }()
+
+	cmd := shellCommand(e.command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", errPlugin, request.Operation, err)
+	}
+
+	var response pluginResponse
+
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		return nil, fmt.Errorf("%w: %s: decoding response: %w", errPlugin, request.Operation, err)
+	}
+
+	if response.Error != "" {
+		return nil, fmt.Errorf("%w: %s: %s", errPlugin, request.Operation, response.Error)
+	}
+
+	return &response, nil
+}
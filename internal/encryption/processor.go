@@ -1,21 +1,28 @@
 package encryption
 
 import (
+	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	"github.com/tink-crypto/tink-go/v2/daead"
 	"github.com/tink-crypto/tink-go/v2/tink"
 
-	"github.com/idelchi/gogen/pkg/key"
 	"github.com/idelchi/gonc/internal/config"
 	"github.com/idelchi/gonc/internal/fileutil"
+	"github.com/idelchi/gonc/internal/progress"
+	"github.com/idelchi/gonc/internal/report"
 )
 
 // Processor handles the encryption and decryption of files.
@@ -26,11 +33,29 @@ type Processor struct {
 	// daead provides deterministic authenticated encryption
 	daead tink.DeterministicAEAD
 
+	// daeadMu guards the lazy initialization of daead
+	daeadMu sync.Mutex
+
 	// key stores raw key bytes
 	key []byte
 
+	// provider wraps per-file data keys, nil when files are encrypted with key directly
+	provider Provider
+
+	// selector limits which structured values are encrypted
+	selector *regexp.Regexp
+
 	// results channels processing outcomes to the printer goroutine
 	results chan Result
+
+	// batch stages outputs for an all-or-nothing commit, nil outside of --atomic-batch
+	batch *fileutil.Batch
+
+	// unverified counts outputs that failed --verify
+	unverified int
+
+	// progress counts processed bytes and files for --progress, nil otherwise
+	progress *progress.Tracker
 }
 
 const (
@@ -45,23 +70,7 @@ const (
 //
 //nolint:funlen,cyclop
 func NewProcessor(cfg *config.Config) (*Processor, error) {
-	var (
-		encryptionKey []byte
-		err           error
-	)
-
-	switch {
-	case cfg.Key.String != "":
-		encryptionKey, err = key.FromHex(cfg.Key.String)
-	case cfg.Key.File != "":
-		encryptionKey, err = os.ReadFile(cfg.Key.File)
-		if err != nil {
-			return nil, fmt.Errorf("reading key file: %w", err)
-		}
-
-		encryptionKey, err = key.FromHex(string(encryptionKey))
-	}
-
+	encryptionKey, err := loadKey(cfg.Key)
 	if err != nil {
 		return nil, fmt.Errorf("reading key: %w", err)
 	}
@@ -72,9 +81,32 @@ func NewProcessor(cfg *config.Config) (*Processor, error) {
 		results: make(chan Result, len(cfg.Files)),
 	}
 
-	if cfg.Decrypt {
+	if cfg.Verify && cfg.Structured {
+		return nil, fmt.Errorf("%w: --verify cannot be combined with --structured", config.ErrUsage)
+	}
+
+	if cfg.EncryptedRegex != "" {
+		if processor.selector, err = regexp.Compile(cfg.EncryptedRegex); err != nil {
+			return nil, fmt.Errorf("compiling --encrypted-regex: %w", err)
+		}
+	}
+
+	if cfg.Key.Provider != "" {
+		if cfg.Deterministic || cfg.Structured {
+			return nil, fmt.Errorf("%w: --key-provider requires randomized whole-file encryption", config.ErrUsage)
+		}
+
+		if processor.provider, err = NewProvider(cfg.Key.Provider, cfg.Key.Insecure); err != nil {
+			return nil, fmt.Errorf("creating key provider: %w", err)
+		}
+
+		// A key, if also given, is only used for envelopes written without a provider.
+		return processor, nil
+	}
+
+	if cfg.Decrypt || cfg.Status {
 		if len(encryptionKey) != AesSivKeySize && len(encryptionKey) != AesKeySize {
-			return nil, errors.New("decrypt: key must be 32 or 64 bytes (64 or 128 hex characters)")
+			return nil, fmt.Errorf("%w: decrypt: key must be 32 or 64 bytes (64 or 128 hex characters)", config.ErrUsage)
 		}
 
 		return processor, nil
@@ -82,7 +114,7 @@ func NewProcessor(cfg *config.Config) (*Processor, error) {
 
 	if cfg.Deterministic { //nolint:nestif
 		if len(encryptionKey) != AesSivKeySize {
-			return nil, errors.New("encrypt: deterministic mode requires 64-byte key (128 hex characters)")
+			return nil, fmt.Errorf("%w: encrypt: deterministic mode requires 64-byte key (128 hex characters)", config.ErrUsage)
 		}
 
 		kh, err := newDeterministicAEADKeyHandle(encryptionKey)
@@ -98,7 +130,7 @@ func NewProcessor(cfg *config.Config) (*Processor, error) {
 		processor.daead = daeadPrimitive
 	} else { //nolint:gocritic
 		if len(encryptionKey) != AesKeySize {
-			return nil, errors.New("encrypt: randomized mode requires 32-byte key (64 hex characters)")
+			return nil, fmt.Errorf("%w: encrypt: randomized mode requires 32-byte key (64 hex characters)", config.ErrUsage)
 		}
 	}
 
@@ -106,14 +138,36 @@ func NewProcessor(cfg *config.Config) (*Processor, error) {
 }
 
 // ProcessFiles concurrently processes all files specified in the configuration.
-// It encrypts or decrypts files based on the configuration settings.
+// It encrypts or decrypts files based on the configuration settings, reporting each file to reporter.
 // Returns the number of successfully processed files and the number of errors.
+// With --atomic-batch, outputs are only moved into place once every file succeeded.
+// Canceling ctx aborts the files in flight and removes their temporary files.
+// With --fail-fast, no new files are started after the first error.
 //
-//nolint:cyclop,gocognit
-func (p *Processor) ProcessFiles() (processed, errored int, totalSize int64, err error) {
-	group := errgroup.Group{}
+//nolint:cyclop,gocognit,funlen
+func (p *Processor) ProcessFiles(
+	ctx context.Context,
+	reporter *report.Reporter,
+) (processed, errored int, totalSize int64, err error) {
+	group, scheduling := &errgroup.Group{}, ctx
+
+	if p.cfg.FailFast {
+		group, scheduling = errgroup.WithContext(ctx)
+	}
+
 	group.SetLimit(p.cfg.Parallel)
 
+	if p.cfg.AtomicBatch {
+		p.batch = fileutil.NewBatch(p.cfg.Delete, p.cfg.Durability())
+	}
+
+	p.progress = NewTracker(p.cfg)
+
+	reporter.Through(p.progress.Writer)
+	p.progress.Start()
+
+	var staged []Result
+
 	done := make(chan struct{})
 
 	go func() {
@@ -123,43 +177,68 @@ func (p *Processor) ProcessFiles() (processed, errored int, totalSize int64, err
 			if result.Error != nil {
 				errored++
 
-				fmt.Fprintf(os.Stderr, "Error processing %q: %v\n", result.Input, result.Error)
-			} else {
-				processed++
+				if errors.Is(result.Error, ErrVerification) {
+					p.unverified++
+				}
 
-				totalSize += result.OutputSize
+				reporter.File(p.event(result))
 
-				if !p.cfg.Quiet {
-					fmt.Printf("Processed %q -> %q\n", result.Input, result.Output) //nolint:forbidigo
-				}
+				continue
 			}
 
-			if p.cfg.Delete && result.Error == nil {
+			if p.batch != nil {
+				staged = append(staged, result)
+
+				continue
+			}
+
+			processed++
+
+			totalSize += result.OutputSize
+
+			if p.cfg.Delete && result.Output != result.Input {
 				if err := os.Remove(result.Input); err != nil {
 					fmt.Fprintf(os.Stderr, "Error deleting %q: %v\n", result.Input, err)
-				}
-
-				if !p.cfg.Quiet {
-					fmt.Printf("Deleted %q\n", result.Input) //nolint:forbidigo
+				} else {
+					result.Deleted = true
 				}
 			}
+
+			reporter.File(p.event(result))
 		}
 	}()
 
+	var started atomic.Int64
+
 	for _, file := range p.cfg.Files {
+		if scheduling.Err() != nil {
+			break
+		}
+
 		group.Go(func() error {
-			outPath := p.outputPath(file)
+			// A file may be queued while the first error cancels scheduling.
+			if scheduling.Err() != nil {
+				return nil
+			}
+
+			started.Add(1)
 
-			size, err := p.processFile(file, outPath)
-			if err != nil {
-				p.results <- Result{Input: file, Error: err}
+			result := Result{Input: file, Output: OutputPath(file, p.cfg)}
 
-				return err
+			if info, err := os.Stat(file); err == nil {
+				result.InputSize = info.Size()
 			}
 
-			p.results <- Result{Input: file, Output: outPath, OutputSize: size}
+			start := time.Now()
+
+			result.Error = p.processFile(ctx, &result)
+			result.Duration = time.Since(start)
 
-			return nil
+			p.progress.FileDone()
+
+			p.results <- result
+
+			return result.Error
 		})
 	}
 
@@ -169,6 +248,22 @@ func (p *Processor) ProcessFiles() (processed, errored int, totalSize int64, err
 
 	<-done // Wait for printer to finish
 
+	p.progress.Stop()
+
+	skipped := len(p.cfg.Files) - int(started.Load())
+
+	reporter.Skip(skipped)
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = fmt.Errorf("interrupted: %w", ctxErr)
+	} else if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Stopped after the first error, %d files not processed\n", skipped)
+	}
+
+	if p.batch != nil {
+		return p.commitBatch(reporter, staged, errored, err)
+	}
+
 	if err != nil {
 		return processed, errored, totalSize, fmt.Errorf("processing files: %w", err)
 	}
@@ -176,29 +271,205 @@ func (p *Processor) ProcessFiles() (processed, errored int, totalSize int64, err
 	return processed, errored, totalSize, nil
 }
 
+// NewTracker creates the progress tracker for --progress over the resolved files, or returns nil.
+func NewTracker(cfg *config.Config) *progress.Tracker {
+	if !cfg.Progress {
+		return nil
+	}
+
+	var total int64
+
+	for _, file := range cfg.Files {
+		if info, err := os.Stat(file); err == nil {
+			total += info.Size()
+		}
+	}
+
+	return progress.New(len(cfg.Files), total)
+}
+
+// VerifyFailures returns the number of outputs that failed --verify in ProcessFiles.
+func (p *Processor) VerifyFailures() int {
+	return p.unverified
+}
+
+// commitBatch moves the staged outputs of an --atomic-batch run into place, or discards all of them
+// when any file failed.
+func (p *Processor) commitBatch(
+	reporter *report.Reporter,
+	staged []Result,
+	errored int,
+	err error,
+) (int, int, int64, error) {
+	if err != nil {
+		p.batch.Discard()
+
+		for _, result := range staged {
+			result.Error = fileutil.ErrRolledBack
+			reporter.File(p.event(result))
+		}
+
+		return 0, errored, 0, fmt.Errorf("processing files, batch rolled back: %w", err)
+	}
+
+	deleted, err := p.batch.Commit()
+	if err != nil {
+		for _, result := range staged {
+			result.Error = fileutil.ErrRolledBack
+			reporter.File(p.event(result))
+		}
+
+		return 0, len(staged), 0, fmt.Errorf("committing batch: %w", err)
+	}
+
+	removed := make(map[string]bool, len(deleted))
+
+	for _, file := range deleted {
+		removed[file] = true
+	}
+
+	var totalSize int64
+
+	for _, result := range staged {
+		totalSize += result.OutputSize
+
+		result.Deleted = removed[result.Input]
+		reporter.File(p.event(result))
+	}
+
+	return len(staged), 0, totalSize, nil
+}
+
+// event converts a result for the reporter.
+func (p *Processor) event(result Result) report.File {
+	command := "encrypt"
+
+	if p.cfg.Decrypt {
+		command = "decrypt"
+	}
+
+	return report.File{
+		Command:    command,
+		Input:      result.Input,
+		Output:     result.Output,
+		InputSize:  result.InputSize,
+		OutputSize: result.OutputSize,
+		Mode:       result.Mode,
+		Duration:   report.Milliseconds(result.Duration),
+		Unchanged:  result.Unchanged,
+		Deleted:    result.Deleted,
+		Err:        result.Error,
+	}
+}
+
+// Encrypt writes an envelope holding the data read from reader to writer, using the configured mode.
+func (p *Processor) Encrypt(reader io.Reader, writer io.Writer, executable bool) error {
+	key, err := p.newFileKey()
+	if err != nil {
+		return err
+	}
+
+	defer key.destroy()
+
+	return p.encrypt(reader, writer, executable, key)
+}
+
+// Decrypt authenticates and decrypts the envelope read from reader into writer.
+// It returns whether the original file was executable.
+func (p *Processor) Decrypt(reader io.Reader, writer io.Writer) (bool, error) {
+	info, err := p.decrypt(reader, writer)
+
+	return info.executable, err
+}
+
+// EncryptSized encrypts size bytes read from reader, like Encrypt. The writer is only requested from open
+// once the exact size of the envelope is known, so the envelope can be streamed into a sized entry of an archive.
+func (p *Processor) EncryptSized(
+	reader io.Reader,
+	size int64,
+	executable bool,
+	open func(size int64) (io.Writer, error),
+) error {
+	key, err := p.newFileKey()
+	if err != nil {
+		return err
+	}
+
+	defer key.destroy()
+
+	prefix, err := envelopeBytes(newEnvelopeHeader(p.mode(), executable, key.wrapped != nil), key.wrapped)
+	if err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+
+	writer, err := open(int64(len(prefix)) + payloadSize(p.mode(), size))
+	if err != nil {
+		return err
+	}
+
+	return p.encrypt(reader, writer, executable, key)
+}
+
+// DecryptSized decrypts an envelope of size bytes read from reader, like Decrypt. The writer is only requested
+// from open once the size of the plaintext is known from the header. The plaintext is streamed before the
+// envelope is authenticated, so after an error the writer holds data that must be discarded.
+func (p *Processor) DecryptSized(reader io.Reader, size int64, open func(size int64) (io.Writer, error)) error {
+	header, info, err := readEnvelopeHeader(reader)
+	if err != nil {
+		return err
+	}
+
+	prefix, err := envelopeBytes(header, info.wrapped)
+	if err != nil {
+		return fmt.Errorf("%w: reading wrapped data key: %w", ErrProcessing, err)
+	}
+
+	plain, err := plaintextSize(info.mode, size-int64(len(prefix)))
+	if err != nil {
+		return err
+	}
+
+	writer, err := open(plain)
+	if err != nil {
+		return err
+	}
+
+	return p.decryptPayload(reader, writer, header, info)
+}
+
+// Digest returns the keyed digest of the plaintext read from reader.
+func (p *Processor) Digest(reader io.Reader) ([]byte, error) {
+	return plaintextDigest(p.key, reader)
+}
+
+// ProcessFile encrypts or decrypts a single file to outPath, replacing it atomically.
+func (p *Processor) ProcessFile(filename, outPath string) (int64, error) {
+	result := Result{Input: filename, Output: outPath}
+
+	err := p.processFile(context.Background(), &result)
+
+	return result.OutputSize, err
+}
+
 // encrypt reads data from r, encrypts it using the configured mode,
 // and writes the result to w. The isExec parameter preserves the executable bit information.
-func (p *Processor) encrypt(reader io.Reader, writer io.Writer, isExec bool) error {
-	var (
-		mode envelopeMode
-		err  error
-	)
+// Randomized payloads are encrypted with key, whose wrapping, if any, is stored in the header.
+func (p *Processor) encrypt(reader io.Reader, writer io.Writer, isExec bool, key fileKey) error {
+	header := newEnvelopeHeader(p.mode(), isExec, key.wrapped != nil)
 
-	if p.cfg.Deterministic {
-		mode = modeDeterministic
-	} else {
-		mode = modeRandomized
+	written, err := envelopeBytes(header, key.wrapped)
+	if err != nil {
+		return fmt.Errorf("writing header: %w", err)
 	}
 
-	header := newEnvelopeHeader(mode, isExec)
-	if _, err := writer.Write(header); err != nil {
+	if _, err := writer.Write(written); err != nil {
 		return fmt.Errorf("writing header: %w", err)
 	}
 
 	if p.cfg.Deterministic {
 		err = p.encryptDeterministic(reader, writer, header)
 	} else {
-		err = p.encryptRandomized(reader, writer, header)
+		err = p.encryptRandomized(reader, writer, header, key.key)
 	}
 
 	return err
@@ -206,87 +477,157 @@ func (p *Processor) encrypt(reader io.Reader, writer io.Writer, isExec bool) err
 
 // decrypt reads encrypted data from r, decrypts it using the mode specified in the header,
 // and writes the result to w. It returns whether the original file was executable.
-func (p *Processor) decrypt(reader io.Reader, writer io.Writer) (bool, error) {
-	header := make([]byte, envelopeHeaderSize)
-	if _, err := io.ReadFull(reader, header); err != nil {
-		return false, fmt.Errorf("reading header: %w", err)
-	}
-
-	mode, exec, err := parseEnvelopeHeader(header)
+func (p *Processor) decrypt(reader io.Reader, writer io.Writer) (envelopeInfo, error) {
+	header, info, err := readEnvelopeHeader(reader)
 	if err != nil {
-		return false, err
+		return info, err
 	}
 
-	switch mode {
-	case modeDeterministic:
-		if len(p.key) != AesSivKeySize {
-			return false, errors.New("decrypt: deterministic data requires 64-byte key (128 hex characters)")
-		}
+	return info, p.decryptPayload(reader, writer, header, info)
+}
 
-		if p.daead == nil {
-			kh, err := newDeterministicAEADKeyHandle(p.key)
-			if err != nil {
-				return false, fmt.Errorf("creating keyset handle: %w", err)
-			}
+// mode returns the encryption mode of new envelopes.
+func (p *Processor) mode() envelopeMode {
+	if p.cfg.Deterministic {
+		return modeDeterministic
+	}
 
-			daeadPrimitive, err := daead.New(kh)
-			if err != nil {
-				return false, fmt.Errorf("creating DeterministicAEAD: %w", err)
-			}
+	return modeRandomized
+}
 
-			p.daead = daeadPrimitive
+// decryptPayload decrypts the payload following an envelope header read by readEnvelopeHeader.
+func (p *Processor) decryptPayload(reader io.Reader, writer io.Writer, header []byte, info envelopeInfo) error {
+	switch info.mode {
+	case modeDeterministic:
+		if err := p.initDeterministic(); err != nil {
+			return err
 		}
 
-		return exec, p.decryptDeterministic(reader, writer, header)
+		return p.decryptDeterministic(reader, writer, header)
 	case modeRandomized:
-		if len(p.key) != AesKeySize {
-			return false, errors.New("decrypt: randomized data requires 32-byte key (64 hex characters)")
+		key, err := p.keyFor(info)
+		if err != nil {
+			return err
+		}
+
+		defer key.destroy()
+
+		if len(key.key) != AesKeySize {
+			return fmt.Errorf("%w: decrypt: randomized data requires 32-byte key (64 hex characters)", config.ErrUsage)
 		}
 
-		return exec, p.decryptRandomized(reader, writer, header)
+		return p.decryptRandomized(reader, writer, header, key.key)
 	default:
-		return false, errors.New("unknown encryption mode")
+		return errors.New("unknown encryption mode")
+	}
+}
+
+// initDeterministic lazily creates the deterministic AEAD primitive for data
+// whose mode is only known after reading its header.
+func (p *Processor) initDeterministic() error {
+	if len(p.key) != AesSivKeySize {
+		return fmt.Errorf("%w: decrypt: deterministic data requires 64-byte key (128 hex characters)", config.ErrUsage)
+	}
+
+	p.daeadMu.Lock()
+	defer p.daeadMu.Unlock()
+
+	if p.daead != nil {
+		return nil
+	}
+
+	kh, err := newDeterministicAEADKeyHandle(p.key)
+	if err != nil {
+		return fmt.Errorf("creating keyset handle: %w", err)
 	}
+
+	daeadPrimitive, err := daead.New(kh)
+	if err != nil {
+		return fmt.Errorf("creating DeterministicAEAD: %w", err)
+	}
+
+	p.daead = daeadPrimitive
+
+	return nil
 }
 
 // processFile handles the encryption or decryption of a single file.
 // It creates a temporary file for output and performs an atomic rename on completion.
 //
 //nolint:funlen,cyclop,gocognit
-func (p *Processor) processFile(filename, outPath string) (size int64, err error) {
-	tc, err := fileutil.NewTempContext(filename, outPath)
+func (p *Processor) processFile(ctx context.Context, result *Result) (err error) {
+	filename, outPath := result.Input, result.Output
+
+	tc, err := fileutil.NewTempContext(filename, outPath, p.cfg.Durability())
 	if err != nil {
-		return 0, fmt.Errorf("preparing atomic write: %w", err)
+		return fmt.Errorf("preparing atomic write: %w", err)
 	}
 
 	defer tc.CleanupOnError(&err)
 
 	inFile, err := os.Open(filepath.Clean(filename))
 	if err != nil {
-		return 0, fmt.Errorf("opening input file: %w", err)
+		return fmt.Errorf("opening input file: %w", err)
 	}
 	defer inFile.Close()
 
+	reader := p.progress.Reader(fileutil.NewContextReader(ctx, inFile))
+
+	// written hashes the plaintext going into or coming out of the output for --verify.
+	written := sha256.New()
+
 	const ownerReadWrite = 0o600
 
-	if p.cfg.Decrypt { //nolint:nestif
-		execOut, err := p.decrypt(inFile, tc.TmpFile)
+	switch {
+	case p.cfg.Structured:
+		result.Mode = "structured"
+
+		if err := p.transformStructured(reader, tc.TmpFile, filename); err != nil {
+			return fmt.Errorf("processing structured file: %w", err)
+		}
+
+		perm := os.FileMode(ownerReadWrite)
+
+		if tc.IsExec {
+			perm |= 0o111
+		}
+
+		if err := os.Chmod(tc.TmpName, perm); err != nil {
+			return fmt.Errorf("setting file permissions: %w", err)
+		}
+	case p.cfg.Decrypt:
+		info, err := p.decrypt(reader, io.MultiWriter(tc.TmpFile, written))
 		if err != nil {
-			return 0, fmt.Errorf("decrypting file: %w", err)
+			return fmt.Errorf("decrypting file: %w", err)
 		}
 
 		perm := os.FileMode(ownerReadWrite)
 
-		if execOut {
+		result.Mode = info.mode.String()
+
+		if info.executable {
 			perm |= 0o111
 		}
 
 		if err := os.Chmod(tc.TmpName, perm); err != nil {
-			return 0, fmt.Errorf("setting file permissions: %w", err)
+			return fmt.Errorf("setting file permissions: %w", err)
 		}
-	} else {
-		if err := p.encrypt(inFile, tc.TmpFile, tc.IsExec); err != nil {
-			return 0, fmt.Errorf("encrypting file: %w", err)
+	default:
+		result.Mode = modeRandomized.String()
+
+		if p.cfg.Deterministic {
+			result.Mode = modeDeterministic.String()
+		}
+
+		key, err := p.newFileKey()
+		if err != nil {
+			return err
+		}
+
+		defer key.destroy()
+
+		if err := p.encrypt(io.TeeReader(reader, written), tc.TmpFile, tc.IsExec, key); err != nil {
+			return fmt.Errorf("encrypting file: %w", err)
 		}
 
 		perm := os.FileMode(ownerReadWrite)
@@ -296,40 +637,85 @@ func (p *Processor) processFile(filename, outPath string) (size int64, err error
 		}
 
 		if err := os.Chmod(tc.TmpName, perm); err != nil {
-			return 0, fmt.Errorf("setting file permissions: %w", err)
+			return fmt.Errorf("setting file permissions: %w", err)
 		}
 	}
 
-	if err := tc.TmpFile.Close(); err != nil {
-		return 0, fmt.Errorf("closing temporary file: %w", err)
+	if err := tc.Close(p.cfg.Durability()); err != nil {
+		return err
 	}
 
 	if err := inFile.Close(); err != nil {
-		return 0, fmt.Errorf("closing input file: %w", err)
+		return fmt.Errorf("closing input file: %w", err)
+	}
+
+	if p.cfg.Verify {
+		if err := p.verify(tc.TmpName, written.Sum(nil)); err != nil {
+			return err
+		}
+	}
+
+	var same func() (bool, error)
+
+	if !p.cfg.Decrypt && !p.cfg.Structured {
+		same = func() (bool, error) { return p.Matches(filename, outPath) }
 	}
 
-	if err := os.Rename(tc.TmpName, outPath); err != nil {
-		return 0, fmt.Errorf("renaming output file: %w", err)
+	if p.batch != nil {
+		result.OutputSize, err = fileutil.FinalizeOutput(tc.TmpName, p.cfg.PreserveTimestamps, tc.SrcInfo.ModTime())
+		if err != nil {
+			return fmt.Errorf("finalizing output: %w", err)
+		}
+
+		existing, err := p.batch.Stage(filename, outPath, tc.TmpName, p.overwrite(), same)
+		result.Unchanged = existing == fileutil.Unchanged
+
+		return err
 	}
 
-	size, err = fileutil.FinalizeOutput(outPath, p.cfg.PreserveTimestamps, tc.SrcInfo.ModTime())
+	existing, err := p.overwrite().Guard(outPath, tc.TmpName, same)
 	if err != nil {
-		return 0, fmt.Errorf("finalizing output: %w", err)
+		return err
 	}
 
-	return size, nil
+	result.Unchanged = existing == fileutil.Unchanged
+
+	if err := tc.Rename(outPath, p.cfg.Durability()); err != nil {
+		return err
+	}
+
+	result.OutputSize, err = fileutil.FinalizeOutput(outPath, p.cfg.PreserveTimestamps, tc.SrcInfo.ModTime())
+	if err != nil {
+		return fmt.Errorf("finalizing output: %w", err)
+	}
+
+	return nil
 }
 
-// outputPath generates the output file path based on the input filename
-// and the configured suffixes for encryption/decryption.
-func (p *Processor) outputPath(filename string) string {
-	ext := p.cfg.Suffixes.Encrypt
+// overwrite returns the policy for existing outputs.
+func (p *Processor) overwrite() fileutil.Overwrite {
+	return fileutil.Overwrite{Force: p.cfg.Force, Backup: p.cfg.Backup}
+}
 
-	if p.cfg.Decrypt {
-		filename = strings.TrimSuffix(filename, p.cfg.Suffixes.Encrypt)
-		ext = p.cfg.Suffixes.Decrypt
+// OutputPath returns the path the given input is written to, based on the configured
+// suffixes for encryption/decryption. With an output directory, the relative path of the
+// input is mirrored below it.
+func OutputPath(filename string, cfg *config.Config) string {
+	ext := cfg.Suffixes.Encrypt
+
+	if cfg.Decrypt {
+		if !cfg.KeepExt {
+			filename = strings.TrimSuffix(filename, cfg.Suffixes.Encrypt)
+		}
+
+		ext = cfg.Suffixes.Decrypt
+	}
+
+	dir := filepath.Dir(filename)
+
+	if cfg.OutputDir != "" {
+		dir = filepath.Join(cfg.OutputDir, dir)
 	}
 
-	return filepath.Join(filepath.Dir(filename),
-		filepath.Base(filename)+ext)
+	return filepath.Join(dir, filepath.Base(filename)+ext)
 }
@@ -0,0 +1,189 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/keymem"
+)
+
+// Provider wraps and unwraps per-file data keys with a key-encryption key that gonc never sees directly,
+// in the manner of a key management service.
+type Provider interface {
+	// Wrap encrypts a data key and returns it together with the ID of the key-encryption key used,
+	// which is stored in the envelope header.
+	Wrap(dataKey []byte) (wrapped []byte, id string, err error)
+
+	// Unwrap decrypts a data key wrapped under the key-encryption key with the given ID.
+	Unwrap(wrapped []byte, id string) ([]byte, error)
+}
+
+// NewProvider creates the provider described by spec:
+// "local:<path>" for a key-encryption key in a local file, or "exec:<command>" for a plugin.
+// With insecure, a local key file is used even if other users can access it.
+func NewProvider(spec string, insecure bool) (Provider, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+
+	switch {
+	case arg == "":
+		return nil, fmt.Errorf("%w: key provider %q must be local:<path> or exec:<command>", config.ErrUsage, spec)
+	case kind == "local":
+		return newLocalProvider(arg, insecure)
+	case kind == "exec":
+		return &execProvider{command: arg}, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown key provider %q, use local:<path> or exec:<command>", config.ErrUsage, kind)
+	}
+}
+
+// localProvider wraps data keys with AES-256-GCM under a key-encryption key read from a file.
+type localProvider struct {
+	// aead encrypts under the key-encryption key
+	aead cipher.AEAD
+
+	// id names the key-encryption key by its fingerprint
+	id string
+}
+
+// newLocalProvider reads a 32-byte key-encryption key, hex-encoded or as a self-describing key file.
+func newLocalProvider(path string, insecure bool) (*localProvider, error) {
+	kek, err := readKeyFile(path, insecure)
+	if err != nil {
+		return nil, fmt.Errorf("reading key-encryption key: %w", err)
+	}
+
+	if len(kek) != AesKeySize {
+		return nil, fmt.Errorf("%w: key-encryption key must be %d bytes", config.ErrUsage, AesKeySize)
+	}
+
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("creating GCM: %w", err)
+	}
+
+	fingerprint := sha256.Sum256(kek)
+
+	return &localProvider{aead: aead, id: "local:" + hex.EncodeToString(fingerprint[:8])}, nil
+}
+
+// Wrap implements Provider. The wrapped key is the nonce followed by the sealed data key.
+func (l *localProvider) Wrap(dataKey []byte) ([]byte, string, error) {
+	nonce := make([]byte, l.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, "", fmt.Errorf("generating nonce: %w", err)
+	}
+
+	return l.aead.Seal(nonce, nonce, dataKey, []byte(l.id)), l.id, nil
+}
+
+// Unwrap implements Provider.
+func (l *localProvider) Unwrap(wrapped []byte, id string) ([]byte, error) {
+	if id != l.id {
+		return nil, fmt.Errorf("%w: data key was wrapped by %q, not by %q", ErrAuthentication, id, l.id)
+	}
+
+	if len(wrapped) < l.aead.NonceSize() {
+		return nil, fmt.Errorf("%w: wrapped data key too short", ErrProcessing)
+	}
+
+	nonce, sealed := wrapped[:l.aead.NonceSize()], wrapped[l.aead.NonceSize():]
+
+	dataKey, err := l.aead.Open(nil, nonce, sealed, []byte(id))
+	if err != nil {
+		return nil, fmt.Errorf("%w: unwrapping data key: %w", ErrProcessing, ErrAuthentication)
+	}
+
+	return dataKey, nil
+}
+
+// fileKey is the key one envelope is encrypted with, together with its wrapping if it is a data key.
+type fileKey struct {
+	// key encrypts the payload and keys the digest
+	key []byte
+
+	// wrapped is the data key as stored in the header, nil without a provider
+	wrapped *wrappedKey
+
+	// buffer holds a data key until destroy, nil for the configured key
+	buffer *keymem.Buffer
+}
+
+// destroy wipes a data key. The configured key is left alone, as it is shared by the whole run.
+func (k fileKey) destroy() {
+	k.buffer.Destroy()
+}
+
+// newFileKey returns the configured key, or with a provider a fresh data key and its wrapping.
+func (p *Processor) newFileKey() (fileKey, error) {
+	if p.provider == nil {
+		return fileKey{key: p.key}, nil
+	}
+
+	dataKey := keymem.New(AesKeySize)
+
+	if _, err := io.ReadFull(rand.Reader, dataKey.Bytes()); err != nil {
+		dataKey.Destroy()
+
+		return fileKey{}, fmt.Errorf("generating data key: %w", err)
+	}
+
+	wrapped, id, err := p.provider.Wrap(dataKey.Bytes())
+	if err != nil {
+		dataKey.Destroy()
+
+		return fileKey{}, fmt.Errorf("wrapping data key: %w", err)
+	}
+
+	return fileKey{key: dataKey.Bytes(), wrapped: &wrappedKey{provider: id, key: wrapped}, buffer: dataKey}, nil
+}
+
+// keyFor returns the key the envelope described by info was encrypted with,
+// unwrapping its data key through the provider if it carries one.
+func (p *Processor) keyFor(info envelopeInfo) (fileKey, error) {
+	if info.wrapped == nil {
+		return fileKey{key: p.key}, nil
+	}
+
+	if p.provider == nil {
+		return fileKey{}, fmt.Errorf("%w: data key is wrapped by %q, pass --key-provider",
+			config.ErrUsage, info.wrapped.provider)
+	}
+
+	dataKey, err := unwrap(p.provider, info.wrapped)
+	if err != nil {
+		return fileKey{}, err
+	}
+
+	return fileKey{key: dataKey.Bytes(), wrapped: info.wrapped, buffer: dataKey}, nil
+}
+
+// unwrap recovers a data key with provider into locked memory and checks its size.
+func unwrap(provider Provider, wrapped *wrappedKey) (*keymem.Buffer, error) {
+	plain, err := provider.Unwrap(wrapped.key, wrapped.provider)
+	if err != nil {
+		return nil, fmt.Errorf("unwrapping data key: %w", err)
+	}
+
+	defer keymem.Wipe(plain)
+
+	if len(plain) != AesKeySize {
+		return nil, fmt.Errorf("%w: unwrapped data key must be %d bytes", ErrProcessing, AesKeySize)
+	}
+
+	dataKey := keymem.New(AesKeySize)
+	copy(dataKey.Bytes(), plain)
+
+	return dataKey, nil
+}
@@ -8,20 +8,25 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io"
+
+	"github.com/idelchi/gonc/internal/keymem"
 )
 
 const randomizedBufferSize = 4096
 
-func (p *Processor) encryptRandomized(reader io.Reader, writer io.Writer, header []byte) error {
-	if len(p.key) != AesKeySize {
+func (p *Processor) encryptRandomized(reader io.Reader, writer io.Writer, header, key []byte) error {
+	if len(key) != AesKeySize {
 		return fmt.Errorf("encrypt: randomized mode requires %d-byte key", AesKeySize)
 	}
 
-	encKey, macKey, err := deriveRandomizedKeys(p.key)
+	encKey, macKey, err := deriveRandomizedKeys(key)
 	if err != nil {
 		return err
 	}
 
+	defer keymem.Wipe(encKey)
+	defer keymem.Wipe(macKey)
+
 	block, err := aes.NewCipher(encKey)
 	if err != nil {
 		return fmt.Errorf("creating cipher: %w", err)
@@ -74,16 +79,19 @@ func (p *Processor) encryptRandomized(reader io.Reader, writer io.Writer, header
 }
 
 //nolint:gocognit
-func (p *Processor) decryptRandomized(reader io.Reader, writer io.Writer, header []byte) error {
-	if len(p.key) != AesKeySize {
+func (p *Processor) decryptRandomized(reader io.Reader, writer io.Writer, header, key []byte) error {
+	if len(key) != AesKeySize {
 		return fmt.Errorf("decrypt: randomized mode requires %d-byte key", AesKeySize)
 	}
 
-	encKey, macKey, err := deriveRandomizedKeys(p.key)
+	encKey, macKey, err := deriveRandomizedKeys(key)
 	if err != nil {
 		return err
 	}
 
+	defer keymem.Wipe(encKey)
+	defer keymem.Wipe(macKey)
+
 	mac := hmac.New(sha256.New, macKey)
 	mac.Write(header)
 
@@ -145,7 +153,7 @@ func (p *Processor) decryptRandomized(reader io.Reader, writer io.Writer, header
 	}
 
 	if !hmac.Equal(mac.Sum(nil), tagBuffer) {
-		return fmt.Errorf("%w: authentication failed", ErrProcessing)
+		return fmt.Errorf("%w: %w", ErrProcessing, ErrAuthentication)
 	}
 
 	return nil
@@ -1,5 +1,7 @@
 package encryption
 
+import "time"
+
 // Result represents the outcome of processing a single file.
 type Result struct {
 	// Input file path
@@ -8,9 +10,24 @@ type Result struct {
 	// Output file path
 	Output string
 
+	// Input file size in bytes
+	InputSize int64
+
 	// Output file size in bytes
 	OutputSize int64
 
+	// Mode the file was processed with: deterministic, randomized or structured
+	Mode string
+
+	// Duration of the processing
+	Duration time.Duration
+
+	// Unchanged reports that the existing output already held the same content
+	Unchanged bool
+
+	// Deleted reports whether the input was removed
+	Deleted bool
+
 	// Any error that occurred during processing
 	Error error
 }
@@ -0,0 +1,132 @@
+package encryption
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/fileutil"
+)
+
+// Rewrapper moves envelopes from one key-encryption key to another by re-wrapping their data keys.
+// Payloads are copied as they are, since the data keys they are encrypted with do not change.
+type Rewrapper struct {
+	// cfg contains runtime configuration options
+	cfg *config.Config
+
+	// from unwraps the current data keys
+	from Provider
+
+	// to wraps them again
+	to Provider
+}
+
+// NewRewrapper creates a Rewrapper from --from-provider to --key-provider.
+func NewRewrapper(cfg *config.Config) (*Rewrapper, error) {
+	if cfg.FromProvider == "" || cfg.Key.Provider == "" {
+		return nil, fmt.Errorf("%w: rewrap requires --from-provider and --key-provider", config.ErrUsage)
+	}
+
+	from, err := NewProvider(cfg.FromProvider, cfg.Key.Insecure)
+	if err != nil {
+		return nil, fmt.Errorf("creating key provider: %w", err)
+	}
+
+	to, err := NewProvider(cfg.Key.Provider, cfg.Key.Insecure)
+	if err != nil {
+		return nil, fmt.Errorf("creating key provider: %w", err)
+	}
+
+	return &Rewrapper{cfg: cfg, from: from, to: to}, nil
+}
+
+// RewrapFile re-wraps the data key of the envelope at path in place and returns the new file size.
+// Envelopes the target provider already unwraps are left untouched and reported as unchanged,
+// so that an interrupted rotation can simply be run again.
+func (r *Rewrapper) RewrapFile(path string) (unchanged bool, size int64, err error) {
+	inFile, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return false, 0, fmt.Errorf("opening input file: %w", err)
+	}
+	defer inFile.Close()
+
+	reader := bufio.NewReader(inFile)
+
+	header, info, err := readEnvelopeHeader(reader)
+	if err != nil {
+		return false, 0, err
+	}
+
+	if info.wrapped == nil {
+		return false, 0, fmt.Errorf("%w: envelope has no wrapped data key", ErrProcessing)
+	}
+
+	dataKey, err := unwrap(r.from, info.wrapped)
+	if err != nil {
+		if current, unwrapErr := unwrap(r.to, info.wrapped); unwrapErr == nil {
+			current.Destroy()
+
+			stat, err := inFile.Stat()
+			if err != nil {
+				return false, 0, fmt.Errorf("getting file info for %q: %w", path, err)
+			}
+
+			return true, stat.Size(), nil
+		}
+
+		return false, 0, err
+	}
+
+	defer dataKey.Destroy()
+
+	wrapped, id, err := r.to.Wrap(dataKey.Bytes())
+	if err != nil {
+		return false, 0, fmt.Errorf("wrapping data key: %w", err)
+	}
+
+	out, err := envelopeBytes(header, &wrappedKey{provider: id, key: wrapped})
+	if err != nil {
+		return false, 0, fmt.Errorf("writing header: %w", err)
+	}
+
+	tc, err := fileutil.NewTempContext(path, path, r.cfg.Durability())
+	if err != nil {
+		return false, 0, fmt.Errorf("preparing atomic write: %w", err)
+	}
+
+	defer tc.CleanupOnError(&err)
+
+	if _, err = tc.TmpFile.Write(out); err != nil {
+		return false, 0, fmt.Errorf("writing header: %w", err)
+	}
+
+	if _, err = io.Copy(tc.TmpFile, reader); err != nil {
+		return false, 0, fmt.Errorf("copying payload: %w", err)
+	}
+
+	if err = os.Chmod(tc.TmpName, tc.SrcInfo.Mode().Perm()); err != nil {
+		return false, 0, fmt.Errorf("setting file permissions: %w", err)
+	}
+
+	if err = tc.Close(r.cfg.Durability()); err != nil {
+		return false, 0, err
+	}
+
+	if err = inFile.Close(); err != nil {
+		return false, 0, fmt.Errorf("closing input file: %w", err)
+	}
+
+	if err = tc.Rename(path, r.cfg.Durability()); err != nil {
+		return false, 0, err
+	}
+
+	size, err = fileutil.FinalizeOutput(path, r.cfg.PreserveTimestamps, tc.SrcInfo.ModTime())
+	if err != nil {
+		return false, 0, fmt.Errorf("finalizing output: %w", err)
+	}
+
+	return false, size, nil
+}
@@ -0,0 +1,63 @@
+package encryption
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/idelchi/gonc/internal/structured"
+)
+
+// transformStructured encrypts or decrypts the values of a structured document, leaving keys,
+// comments and layout untouched. The format is detected from the plaintext name of the file.
+func (p *Processor) transformStructured(reader io.Reader, writer io.Writer, name string) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("reading input: %w", err)
+	}
+
+	doc, err := structured.Parse(strings.TrimSuffix(name, p.cfg.Suffixes.Encrypt), data)
+	if err != nil {
+		return fmt.Errorf("parsing document: %w", err)
+	}
+
+	var edits []structured.Edit
+
+	for _, leaf := range doc.Leaves() {
+		sealed := leaf.Kind == structured.String && IsSealed(leaf.Value)
+
+		switch {
+		case p.cfg.Decrypt && sealed:
+			kind, value, literal, err := p.OpenValue(leaf.Value, leaf.Path)
+			if err != nil {
+				return err
+			}
+
+			edits = append(edits, structured.Edit{Leaf: leaf, Kind: kind, Value: value, Literal: literal})
+		case !p.cfg.Decrypt && !sealed && leaf.Kind != structured.Null && p.selects(leaf.Path):
+			armored, err := p.SealValue(leaf.Kind, leaf.Value, doc.Literal(leaf), leaf.Path)
+			if err != nil {
+				return err
+			}
+
+			edits = append(edits, structured.Edit{Leaf: leaf, Kind: structured.String, Value: armored})
+		}
+	}
+
+	out, err := doc.Apply(edits)
+	if err != nil {
+		return fmt.Errorf("rewriting document: %w", err)
+	}
+
+	if _, err := writer.Write(out); err != nil {
+		return fmt.Errorf("writing output: %w", err)
+	}
+
+	return nil
+}
+
+// selects reports whether the value at path should be encrypted.
+// Without --encrypted-regex every value is selected.
+func (p *Processor) selects(path string) bool {
+	return p.selector == nil || p.selector.MatchString(path)
+}
@@ -0,0 +1,238 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/keymem"
+	"github.com/idelchi/gonc/internal/structured"
+)
+
+const (
+	armorPrefix = "GONC["
+	armorSuffix = "]"
+)
+
+// IsSealed reports whether a value is an armored gonc ciphertext.
+func IsSealed(value string) bool {
+	return strings.HasPrefix(value, armorPrefix) && strings.HasSuffix(value, armorSuffix)
+}
+
+// SealValue encrypts a single structured value into an armored string.
+// The document path is bound as associated data, so ciphertexts cannot be moved between paths.
+// For strings, the original source literal may be sealed alongside the value, so decryption
+// restores the exact quoting style.
+func (p *Processor) SealValue(kind structured.Kind, value, literal, path string) (string, error) {
+	return p.sealValue(p.mode(), kind, value, literal, path)
+}
+
+// ResealValue encrypts value in place of an armored string, keeping the mode it was sealed with.
+func (p *Processor) ResealValue(armored string, kind structured.Kind, value, path string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(
+		strings.TrimSuffix(strings.TrimPrefix(armored, armorPrefix), armorSuffix))
+	if err != nil {
+		return "", fmt.Errorf("%w: decoding %q: %w", ErrProcessing, path, err)
+	}
+
+	_, info, err := readEnvelopeHeader(bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+
+	if info.mode == modeDeterministic {
+		if err := p.initDeterministic(); err != nil {
+			return "", err
+		}
+	}
+
+	return p.sealValue(info.mode, kind, value, "", path)
+}
+
+// sealValue encrypts a single structured value with the given mode.
+func (p *Processor) sealValue(mode envelopeMode, kind structured.Kind, value, literal, path string) (string, error) {
+	plaintext := []byte{byte(kind)}
+
+	if kind == structured.String {
+		plaintext = binary.AppendUvarint(plaintext, uint64(len(value)))
+		plaintext = append(plaintext, value...)
+		plaintext = append(plaintext, literal...)
+	} else {
+		plaintext = append(plaintext, value...)
+	}
+
+	var (
+		payload []byte
+		err     error
+	)
+
+	header := newEnvelopeHeader(mode, false, false)
+
+	switch mode {
+	case modeDeterministic:
+		payload, err = p.daead.EncryptDeterministically(plaintext, valueAssociatedData(header, path))
+	case modeRandomized:
+		payload, err = p.sealRandomized(plaintext, header, path)
+	default:
+		err = errors.New("unknown encryption mode")
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("sealing %q: %w", path, err)
+	}
+
+	return armorPrefix + base64.StdEncoding.EncodeToString(append(header, payload...)) + armorSuffix, nil
+}
+
+// OpenValue decrypts an armored string sealed for the given path.
+// It returns the value type, the decoded value and the original literal, which is empty when unknown.
+//
+//nolint:cyclop // decoding with per-mode and per-type branches
+func (p *Processor) OpenValue(armored, path string) (structured.Kind, string, string, error) {
+	data, err := base64.StdEncoding.DecodeString(
+		strings.TrimSuffix(strings.TrimPrefix(armored, armorPrefix), armorSuffix))
+	if err != nil {
+		return 0, "", "", fmt.Errorf("%w: decoding %q: %w", ErrProcessing, path, err)
+	}
+
+	reader := bytes.NewReader(data)
+
+	header, info, err := readEnvelopeHeader(reader)
+	if err != nil {
+		return 0, "", "", err
+	}
+
+	payload := data[len(header):]
+
+	var plaintext []byte
+
+	switch info.mode {
+	case modeDeterministic:
+		if err := p.initDeterministic(); err != nil {
+			return 0, "", "", err
+		}
+
+		plaintext, err = p.daead.DecryptDeterministically(payload, valueAssociatedData(header, path))
+		if err != nil {
+			return 0, "", "", fmt.Errorf("%w: opening %q: %w", ErrProcessing, path, ErrAuthentication)
+		}
+	case modeRandomized:
+		if plaintext, err = p.openRandomized(payload, header, path); err != nil {
+			return 0, "", "", err
+		}
+	default:
+		return 0, "", "", errors.New("unknown encryption mode")
+	}
+
+	if len(plaintext) == 0 {
+		return 0, "", "", fmt.Errorf("%w: opening %q: missing value type", ErrProcessing, path)
+	}
+
+	kind, rest := structured.Kind(plaintext[0]), plaintext[1:]
+
+	if kind != structured.String {
+		return kind, string(rest), "", nil
+	}
+
+	length, n := binary.Uvarint(rest)
+	if n <= 0 || length > uint64(len(rest)-n) {
+		return 0, "", "", fmt.Errorf("%w: opening %q: malformed string value", ErrProcessing, path)
+	}
+
+	rest = rest[n:]
+
+	return kind, string(rest[:length]), string(rest[length:]), nil
+}
+
+// sealRandomized encrypts a short value with AES-CTR and authenticates it with HMAC-SHA256.
+func (p *Processor) sealRandomized(plaintext, header []byte, path string) ([]byte, error) {
+	if len(p.key) != AesKeySize {
+		return nil, fmt.Errorf("%w: encrypt: randomized mode requires %d-byte key", config.ErrUsage, AesKeySize)
+	}
+
+	encKey, macKey, err := deriveRandomizedKeys(p.key)
+	if err != nil {
+		return nil, err
+	}
+
+	defer keymem.Wipe(encKey)
+	defer keymem.Wipe(macKey)
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	out := make([]byte, aes.BlockSize+len(plaintext), aes.BlockSize+len(plaintext)+envelopeTagSize)
+	if _, err := io.ReadFull(rand.Reader, out[:aes.BlockSize]); err != nil {
+		return nil, fmt.Errorf("generating IV: %w", err)
+	}
+
+	cipher.NewCTR(block, out[:aes.BlockSize]).XORKeyStream(out[aes.BlockSize:], plaintext)
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(valueAssociatedData(header, path))
+	mac.Write(out)
+
+	return mac.Sum(out), nil
+}
+
+// openRandomized verifies and decrypts a value sealed by sealRandomized.
+func (p *Processor) openRandomized(payload, header []byte, path string) ([]byte, error) {
+	if len(p.key) != AesKeySize {
+		return nil, fmt.Errorf("%w: decrypt: randomized data requires 32-byte key (64 hex characters)", config.ErrUsage)
+	}
+
+	if len(payload) < aes.BlockSize+envelopeTagSize {
+		return nil, fmt.Errorf("%w: opening %q: value too short", ErrProcessing, path)
+	}
+
+	encKey, macKey, err := deriveRandomizedKeys(p.key)
+	if err != nil {
+		return nil, err
+	}
+
+	defer keymem.Wipe(encKey)
+	defer keymem.Wipe(macKey)
+
+	body, tag := payload[:len(payload)-envelopeTagSize], payload[len(payload)-envelopeTagSize:]
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(valueAssociatedData(header, path))
+	mac.Write(body)
+
+	if !hmac.Equal(mac.Sum(nil), tag) {
+		return nil, fmt.Errorf("%w: opening %q: %w", ErrProcessing, path, ErrAuthentication)
+	}
+
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	plaintext := make([]byte, len(body)-aes.BlockSize)
+	cipher.NewCTR(block, body[:aes.BlockSize]).XORKeyStream(plaintext, body[aes.BlockSize:])
+
+	return plaintext, nil
+}
+
+// valueAssociatedData binds a value to its header and length-prefixed document path.
+func valueAssociatedData(header []byte, path string) []byte {
+	const lengthSize = 8
+
+	ad := make([]byte, len(header)+lengthSize, len(header)+lengthSize+len(path))
+	copy(ad, header)
+	binary.BigEndian.PutUint64(ad[len(header):], uint64(len(path)))
+
+	return append(ad, path...)
+}
@@ -0,0 +1,43 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrVerification signals that a written output did not read back to the expected plaintext.
+var ErrVerification = errors.New("verification failed")
+
+// verify reopens a written output and checks that it yields the plaintext with the given SHA-256.
+// Ciphertexts are fully decrypted and authenticated; plaintexts are hashed as written.
+func (p *Processor) verify(path string, want []byte) error {
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("%w: reopening output: %w", ErrVerification, err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+
+	if p.cfg.Decrypt {
+		_, err = io.Copy(hasher, file)
+	} else {
+		_, err = p.decrypt(bufio.NewReader(file), hasher)
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: reading output back: %w", ErrVerification, err)
+	}
+
+	if !bytes.Equal(hasher.Sum(nil), want) {
+		return fmt.Errorf("%w: output does not match the input", ErrVerification)
+	}
+
+	return nil
+}
@@ -0,0 +1,382 @@
+package fileutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// JournalName is the journal of an all-or-nothing batch, written to the working directory while it commits.
+const JournalName = ".gonc-journal.json"
+
+// ErrRolledBack marks outputs that were discarded because another file of the batch failed.
+var ErrRolledBack = errors.New("rolled back with the batch")
+
+// Journal states.
+const (
+	// stateCommit means outputs are being moved into place.
+	stateCommit = "commit"
+	// stateDelete means all outputs are in place and inputs and replaced outputs are being removed.
+	stateDelete = "delete"
+)
+
+// BatchEntry is one staged output of a batch.
+type BatchEntry struct {
+	// Input file the output was produced from
+	Input string `json:"input"`
+
+	// Output path the staged file is moved to
+	Output string `json:"output"`
+
+	// Temp is the fully written temporary file
+	Temp string `json:"temp"`
+
+	// Previous holds an existing output while the batch commits
+	Previous string `json:"previous"`
+
+	// Backup keeps the existing output as a backup instead of removing it
+	Backup bool `json:"backup,omitempty"`
+
+	// Existed records that the output was there before the batch, so that a rollback never removes it
+	Existed bool `json:"existed,omitempty"`
+}
+
+// Batch collects staged outputs and moves them into place all at once.
+// Its journal lets an interrupted commit be finished or rolled back by Recover.
+type Batch struct {
+	// State of the commit
+	State string `json:"state"`
+
+	// Delete removes the inputs once every output is in place
+	Delete bool `json:"delete"`
+
+	// Durable syncs the journal and the output directories before inputs are removed
+	Durable bool `json:"durable,omitempty"`
+
+	// Entries are the staged outputs
+	Entries []BatchEntry `json:"entries"`
+
+	mu sync.Mutex
+}
+
+// NewBatch creates an empty batch. With deleteInputs, inputs are removed after a successful commit.
+// With durable, outputs are flushed to stable storage before that.
+func NewBatch(deleteInputs, durable bool) *Batch {
+	return &Batch{Delete: deleteInputs, Durable: durable}
+}
+
+// Stage adds a finished temporary file to the batch instead of moving it into place.
+// The overwrite policy is checked now, so that a commit does not fail on it later.
+func (b *Batch) Stage(
+	input, outPath, tmpName string,
+	overwrite Overwrite,
+	same func() (bool, error),
+) (Existing, error) {
+	existing, err := overwrite.Check(outPath, tmpName, same)
+	if err != nil {
+		return existing, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.Entries = append(b.Entries, BatchEntry{
+		Input:    input,
+		Output:   outPath,
+		Temp:     tmpName,
+		Previous: filepath.Join(filepath.Dir(outPath), ".prev"+strings.TrimPrefix(filepath.Base(tmpName), ".tmp")),
+		Backup:   existing == Differs && overwrite.Backup,
+		Existed:  existing != Missing,
+	})
+
+	return existing, nil
+}
+
+// Discard removes all staged temporary files.
+func (b *Batch) Discard() {
+	for _, entry := range b.Entries {
+		os.Remove(entry.Temp) //nolint:gosec,errcheck // best-effort cleanup
+	}
+}
+
+// Commit moves every staged output into place. If any move fails, all moves are undone.
+// Afterwards inputs are deleted when requested, and the deleted inputs are returned.
+func (b *Batch) Commit() ([]string, error) {
+	b.State = stateCommit
+
+	if err := b.save(); err != nil {
+		b.Discard()
+
+		return nil, err
+	}
+
+	for _, entry := range b.Entries {
+		if err := entry.forward(); err != nil {
+			return nil, errors.Join(
+				fmt.Errorf("committing %q: %w", entry.Output, err),
+				b.rollback(),
+			)
+		}
+	}
+
+	if err := b.sync(); err != nil {
+		return nil, err
+	}
+
+	return b.finish()
+}
+
+// Recover completes a batch interrupted by a previous invocation, if its journal exists.
+// A commit is finished when every staged file is either in place or can still be moved there,
+// and rolled back otherwise.
+// It returns a description of what was done, or "" when there was nothing to recover.
+func Recover() (string, error) {
+	data, err := os.ReadFile(JournalName)
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return "", nil
+	case err != nil:
+		return "", fmt.Errorf("reading batch journal: %w", err)
+	}
+
+	batch := &Batch{}
+
+	if err := json.Unmarshal(data, batch); err != nil {
+		return "", fmt.Errorf("parsing batch journal %q: %w", JournalName, err)
+	}
+
+	if batch.State == stateCommit {
+		for _, entry := range batch.Entries {
+			if entry.lost() {
+				if err := batch.rollback(); err != nil {
+					return "", err
+				}
+
+				return "rolled back", nil
+			}
+		}
+
+		for _, entry := range batch.Entries {
+			if err := entry.forward(); err != nil {
+				return "", errors.Join(fmt.Errorf("committing %q: %w", entry.Output, err), batch.rollback())
+			}
+		}
+
+		if err := batch.sync(); err != nil {
+			return "", err
+		}
+	}
+
+	if _, err := batch.finish(); err != nil {
+		return "", err
+	}
+
+	return "finished", nil
+}
+
+// forward moves an existing output aside and the staged file into place. It is safe to repeat.
+func (e BatchEntry) forward() error {
+	if !exists(e.Temp) {
+		return nil
+	}
+
+	if exists(e.Output) && !exists(e.Previous) {
+		if err := os.Rename(e.Output, e.Previous); err != nil {
+			return fmt.Errorf("moving existing output aside: %w", err)
+		}
+	}
+
+	if err := os.Rename(e.Temp, e.Output); err != nil {
+		return fmt.Errorf("renaming output file: %w", err)
+	}
+
+	return nil
+}
+
+// lost reports whether the staged file is gone without having been moved into place.
+func (e BatchEntry) lost() bool {
+	switch {
+	case exists(e.Temp):
+		return false
+	case e.Existed:
+		return !exists(e.Previous)
+	default:
+		return !exists(e.Output)
+	}
+}
+
+// backward undoes forward, restoring the previous output if there was one.
+// An output is only removed when the batch created it.
+func (e BatchEntry) backward() error {
+	if exists(e.Temp) {
+		if err := os.Remove(e.Temp); err != nil {
+			return fmt.Errorf("removing %q: %w", e.Temp, err)
+		}
+	} else if !exists(e.Previous) && !e.Existed {
+		if err := os.Remove(e.Output); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("removing %q: %w", e.Output, err)
+		}
+	}
+
+	if exists(e.Previous) {
+		if err := os.Rename(e.Previous, e.Output); err != nil {
+			return fmt.Errorf("restoring %q: %w", e.Output, err)
+		}
+	}
+
+	return nil
+}
+
+// sync flushes the directories of all outputs when the batch is durable.
+func (b *Batch) sync() error {
+	if !b.Durable {
+		return nil
+	}
+
+	synced := make(map[string]bool)
+
+	for _, entry := range b.Entries {
+		dir := filepath.Dir(entry.Output)
+
+		if synced[dir] {
+			continue
+		}
+
+		if err := SyncDir(dir); err != nil {
+			return err
+		}
+
+		synced[dir] = true
+	}
+
+	return nil
+}
+
+// rollback undoes every entry and removes the journal.
+func (b *Batch) rollback() error {
+	var errs []error
+
+	for i := len(b.Entries) - 1; i >= 0; i-- {
+		if err := b.Entries[i].backward(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("rolling back, journal %q kept: %w", JournalName, errors.Join(errs...))
+	}
+
+	if err := os.Remove(JournalName); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("removing batch journal: %w", err)
+	}
+
+	return nil
+}
+
+// finish deletes inputs and replaced outputs once every output is in place, then removes the journal.
+func (b *Batch) finish() ([]string, error) {
+	b.State = stateDelete
+
+	if err := b.save(); err != nil {
+		return nil, err
+	}
+
+	var (
+		deleted []string
+		errs    []error
+	)
+
+	for _, entry := range b.Entries {
+		if exists(entry.Previous) {
+			var err error
+
+			if entry.Backup {
+				err = backupAs(entry.Previous, entry.Output)
+			} else {
+				err = os.Remove(entry.Previous)
+			}
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("removing previous %q: %w", entry.Output, err))
+			}
+		}
+
+		if !b.Delete || entry.Input == entry.Output || !exists(entry.Input) {
+			continue
+		}
+
+		if err := os.Remove(entry.Input); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %q: %w", entry.Input, err))
+
+			continue
+		}
+
+		deleted = append(deleted, entry.Input)
+	}
+
+	if len(errs) > 0 {
+		return deleted, errors.Join(errs...)
+	}
+
+	if err := os.Remove(JournalName); err != nil {
+		return deleted, fmt.Errorf("removing batch journal: %w", err)
+	}
+
+	return deleted, nil
+}
+
+// save writes the journal atomically.
+func (b *Batch) save() (err error) {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding batch journal: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(".", ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	defer func() {
+		tmp.Close() //nolint:gosec // best-effort cleanup
+
+		if err != nil {
+			os.Remove(tmp.Name()) //nolint:gosec // best-effort cleanup
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return fmt.Errorf("writing batch journal: %w", err)
+	}
+
+	if err = tmp.Sync(); err != nil {
+		return fmt.Errorf("syncing batch journal: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), JournalName); err != nil {
+		return fmt.Errorf("renaming batch journal: %w", err)
+	}
+
+	if b.Durable {
+		return SyncDir(".")
+	}
+
+	return nil
+}
+
+// exists reports whether a file exists, without following symlinks.
+func exists(path string) bool {
+	_, err := os.Lstat(path)
+
+	return err == nil
+}
@@ -0,0 +1,27 @@
+package fileutil
+
+import (
+	"context"
+	"io"
+)
+
+// contextReader fails reads once its context is canceled.
+type contextReader struct {
+	ctx    context.Context //nolint:containedctx // bound to the lifetime of a single read loop
+	reader io.Reader
+}
+
+// NewContextReader returns a reader that stops with the context's error once ctx is canceled,
+// so that long copies are aborted between reads.
+func NewContextReader(ctx context.Context, reader io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, reader: reader}
+}
+
+// Read implements io.Reader.
+func (r *contextReader) Read(data []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(data) //nolint:wrapcheck // transparent wrapper
+}
@@ -0,0 +1,63 @@
+package fileutil
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// SyncDir flushes a directory to stable storage, making renames and removals within it durable.
+func SyncDir(dir string) error {
+	// Directories cannot be opened for syncing on Windows, where renames are durable once they return.
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	handle, err := os.Open(dir) //nolint:gosec // dir is the parent of a path being written
+	if err != nil {
+		return fmt.Errorf("opening directory %q: %w", dir, err)
+	}
+
+	defer handle.Close()
+
+	if err := handle.Sync(); err != nil {
+		return fmt.Errorf("syncing directory %q: %w", dir, err)
+	}
+
+	return nil
+}
+
+// MkdirAll creates dir and any missing parents. With durable, the parent of every created directory
+// is synced, so outputs written into new directories survive a power loss before inputs are removed.
+func MkdirAll(dir string, perm os.FileMode, durable bool) error {
+	var created []string
+
+	if durable {
+		for missing := filepath.Clean(dir); ; missing = filepath.Dir(missing) {
+			if _, err := os.Stat(missing); !errors.Is(err, fs.ErrNotExist) {
+				break
+			}
+
+			created = append(created, missing)
+
+			if filepath.Dir(missing) == missing {
+				break
+			}
+		}
+	}
+
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return err //nolint:wrapcheck // the caller adds context
+	}
+
+	for i := len(created) - 1; i >= 0; i-- {
+		if err := SyncDir(filepath.Dir(created[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -17,14 +17,23 @@ type TempContext struct {
 }
 
 // NewTempContext stats the source file and creates a temp file for atomic writing.
+// With durable, output directories it creates are synced into their parents.
 // Caller must defer CleanupOnError.
-func NewTempContext(filename, outPath string) (*TempContext, error) {
+func NewTempContext(filename, outPath string, durable bool) (*TempContext, error) {
 	info, err := os.Stat(filename)
 	if err != nil {
 		return nil, fmt.Errorf("getting file info for %q: %w", filename, err)
 	}
 
-	const executableBits = 0o111
+	const (
+		executableBits = 0o111
+		dirPerm        = 0o755
+	)
+
+	// The output directory may not exist yet when outputs are mirrored into another tree.
+	if err := MkdirAll(filepath.Dir(outPath), dirPerm, durable); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
 
 	tmpFile, err := os.CreateTemp(filepath.Dir(outPath), ".tmp-*")
 	if err != nil {
@@ -48,6 +57,35 @@ func (tc *TempContext) CleanupOnError(errp *error) {
 	}
 }
 
+// Close closes the temp file. With durable, its contents are flushed to stable storage first.
+func (tc *TempContext) Close(durable bool) error {
+	if durable {
+		if err := tc.TmpFile.Sync(); err != nil {
+			return fmt.Errorf("syncing temporary file: %w", err)
+		}
+	}
+
+	if err := tc.TmpFile.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	return nil
+}
+
+// Rename moves the temp file to outPath. With durable, the parent directory is synced,
+// so the new output survives a power loss before the input is removed.
+func (tc *TempContext) Rename(outPath string, durable bool) error {
+	if err := os.Rename(tc.TmpName, outPath); err != nil {
+		return fmt.Errorf("renaming output file: %w", err)
+	}
+
+	if durable {
+		return SyncDir(filepath.Dir(outPath))
+	}
+
+	return nil
+}
+
 // FinalizeOutput optionally preserves timestamps and returns the output file size.
 func FinalizeOutput(outPath string, preserveTimestamps bool, modTime time.Time) (int64, error) {
 	if preserveTimestamps {
@@ -0,0 +1,158 @@
+package fileutil
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strconv"
+)
+
+// ErrOutputExists is returned when an output file already exists with different content.
+var ErrOutputExists = errors.New("output exists and differs, use --force or --backup to replace it")
+
+// Overwrite decides what happens to an existing output that differs from the new one.
+type Overwrite struct {
+	// Force replaces the existing output
+	Force bool
+
+	// Backup moves the existing output aside before it is replaced
+	Backup bool
+}
+
+// Existing describes the output a new file is about to replace.
+type Existing int
+
+const (
+	// Missing means there is no output yet.
+	Missing Existing = iota
+	// Unchanged means the output already holds the same content.
+	Unchanged
+	// Differs means the output holds different content.
+	Differs
+)
+
+// Guard checks an existing output at outPath before it is replaced by the file at tmpName.
+// Outputs with the same bytes, or for which same reports true, are replaced silently.
+// A differing output fails with ErrOutputExists, unless the policy forces the replacement
+// or moves the existing output to a backup first. same may be nil.
+func (o Overwrite) Guard(outPath, tmpName string, same func() (bool, error)) (Existing, error) {
+	existing, err := o.Check(outPath, tmpName, same)
+	if err != nil {
+		return existing, err
+	}
+
+	if existing == Differs && o.Backup {
+		return existing, backup(outPath)
+	}
+
+	return existing, nil
+}
+
+// Check is Guard without side effects: it reports how an existing output compares to the
+// file at tmpName, and fails with ErrOutputExists when the policy does not allow replacing it.
+func (o Overwrite) Check(outPath, tmpName string, same func() (bool, error)) (Existing, error) {
+	if _, err := os.Lstat(outPath); errors.Is(err, fs.ErrNotExist) {
+		return Missing, nil
+	}
+
+	equal, err := sameContent(outPath, tmpName)
+	if err != nil {
+		return Missing, err
+	}
+
+	if !equal && same != nil {
+		if equal, err = same(); err != nil {
+			equal = false
+		}
+	}
+
+	switch {
+	case equal:
+		return Unchanged, nil
+	case !o.Force && !o.Backup:
+		return Differs, fmt.Errorf("%q: %w", outPath, ErrOutputExists)
+	default:
+		return Differs, nil
+	}
+}
+
+// backup renames path to the first free <path>.bak, <path>.bak.1, ... name.
+func backup(path string) error {
+	return backupAs(path, path)
+}
+
+// backupAs renames path to the first free <name>.bak, <name>.bak.1, ... name.
+func backupAs(path, name string) error {
+	target := name + ".bak"
+
+	for i := 1; exists(target); i++ {
+		target = name + ".bak." + strconv.Itoa(i)
+	}
+
+	if err := os.Rename(path, target); err != nil {
+		return fmt.Errorf("backing up %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// sameContent reports whether two files hold the same bytes.
+func sameContent(first, second string) (bool, error) {
+	firstInfo, err := os.Stat(first)
+	if err != nil {
+		return false, fmt.Errorf("getting file info for %q: %w", first, err)
+	}
+
+	secondInfo, err := os.Stat(second)
+	if err != nil {
+		return false, fmt.Errorf("getting file info for %q: %w", second, err)
+	}
+
+	if !firstInfo.Mode().IsRegular() || firstInfo.Size() != secondInfo.Size() {
+		return false, nil
+	}
+
+	firstFile, err := os.Open(first) //nolint:gosec // output path computed from resolved files
+	if err != nil {
+		return false, fmt.Errorf("opening %q: %w", first, err)
+	}
+	defer firstFile.Close()
+
+	secondFile, err := os.Open(second) //nolint:gosec // our own temporary file
+	if err != nil {
+		return false, fmt.Errorf("opening %q: %w", second, err)
+	}
+	defer secondFile.Close()
+
+	const bufferSize = 64 * 1024
+
+	firstReader := bufio.NewReaderSize(firstFile, bufferSize)
+	secondReader := bufio.NewReaderSize(secondFile, bufferSize)
+
+	firstBuf, secondBuf := make([]byte, bufferSize), make([]byte, bufferSize)
+
+	for {
+		n, firstErr := io.ReadFull(firstReader, firstBuf)
+		m, secondErr := io.ReadFull(secondReader, secondBuf)
+
+		if n != m || !bytes.Equal(firstBuf[:n], secondBuf[:m]) {
+			return false, nil
+		}
+
+		if errors.Is(firstErr, io.EOF) || errors.Is(firstErr, io.ErrUnexpectedEOF) {
+			return errors.Is(secondErr, io.EOF) || errors.Is(secondErr, io.ErrUnexpectedEOF), nil
+		}
+
+		if firstErr != nil {
+			return false, fmt.Errorf("reading %q: %w", first, firstErr)
+		}
+
+		if secondErr != nil {
+			return false, fmt.Errorf("reading %q: %w", second, secondErr)
+		}
+	}
+}
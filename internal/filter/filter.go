@@ -2,15 +2,20 @@
 package filter
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/idelchi/gonc/internal/config"
 	"github.com/idelchi/gonc/pkg/pathmatch"
 )
 
+// ErrNoFiles is returned by Resolve when no file passed the filter.
+var ErrNoFiles = errors.New("no files matched the provided patterns")
+
 // Filter selects files based on include/exclude patterns using find -path semantics.
 // Empty includes means "match all". Excludes always win.
 type Filter struct {
@@ -41,6 +46,11 @@ func (f *Filter) match(path string, hasIncludes bool) bool {
 	return included && !excluded
 }
 
+// Match reports whether the slash-separated relative path passes the filter.
+func (f *Filter) Match(path string, hasIncludes bool) bool {
+	return f.match(path, hasIncludes)
+}
+
 // normalizePatterns strips leading "./" from patterns so they match cleaned paths.
 func normalizePatterns(patterns []string) []string {
 	for i, p := range patterns {
@@ -113,7 +123,7 @@ func Resolve(args, includes, excludes []string, hasIncludes bool) (files []strin
 	}
 
 	if len(files) == 0 {
-		return nil, scanned, fmt.Errorf("no files matched the provided patterns: %v", args)
+		return nil, scanned, fmt.Errorf("%w: %v", ErrNoFiles, args)
 	}
 
 	return files, scanned, nil
@@ -154,12 +164,12 @@ func walkDir(root string, flt *Filter, hasIncludes bool) (files []string, total
 // validatePath rejects paths that escape the current working directory.
 func validatePath(path string) error {
 	if filepath.IsAbs(path) {
-		return fmt.Errorf("absolute paths are not allowed: %q", path)
+		return fmt.Errorf("%w: absolute paths are not allowed: %q", config.ErrUsage, path)
 	}
 
 	clean := filepath.Clean(path)
 	if strings.HasPrefix(clean, "..") {
-		return fmt.Errorf("paths must be within the current working directory: %q", path)
+		return fmt.Errorf("%w: paths must be within the current working directory: %q", config.ErrUsage, path)
 	}
 
 	return nil
@@ -0,0 +1,47 @@
+// Package keymem keeps key material in memory that is locked against swapping and wiped after use.
+//
+// Buffers are allocated outside of the Go heap where the platform allows it, so that the garbage collector
+// never copies them and their contents do not outlive Destroy. Locking is best effort: when the limit on
+// locked memory is reached, buffers are still wiped but may be swapped out.
+package keymem
+
+// Buffer is a fixed-size region of key material.
+type Buffer struct {
+	// data is the key material
+	data []byte
+
+	// mapped reports whether data was allocated outside of the Go heap and must be released
+	mapped bool
+}
+
+// New allocates a zeroed buffer of size bytes.
+func New(size int) *Buffer {
+	data, mapped := alloc(size)
+
+	return &Buffer{data: data, mapped: mapped}
+}
+
+// Bytes returns the key material. The slice is only valid until Destroy.
+func (b *Buffer) Bytes() []byte {
+	return b.data
+}
+
+// Destroy wipes the buffer and releases its memory. It is safe to call more than once.
+func (b *Buffer) Destroy() {
+	if b == nil || b.data == nil {
+		return
+	}
+
+	Wipe(b.data)
+
+	if b.mapped {
+		release(b.data)
+	}
+
+	b.data = nil
+}
+
+// Wipe overwrites data with zeros.
+func Wipe(data []byte) {
+	clear(data)
+}
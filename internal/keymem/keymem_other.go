@@ -0,0 +1,11 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package keymem
+
+// alloc allocates on the Go heap, as memory cannot be locked on this platform.
+func alloc(size int) ([]byte, bool) {
+	return make([]byte, max(size, 0)), false
+}
+
+// release is never called, as alloc does not map memory.
+func release([]byte) {}
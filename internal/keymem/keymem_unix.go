@@ -0,0 +1,27 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package keymem
+
+import "golang.org/x/sys/unix"
+
+// alloc maps anonymous memory and locks it, falling back to the Go heap if mapping fails.
+func alloc(size int) ([]byte, bool) {
+	if size <= 0 {
+		return []byte{}, false
+	}
+
+	data, err := unix.Mmap(-1, 0, size, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_ANON|unix.MAP_PRIVATE)
+	if err != nil {
+		return make([]byte, size), false
+	}
+
+	_ = unix.Mlock(data) //nolint:errcheck // best effort, limited by RLIMIT_MEMLOCK
+
+	return data, true
+}
+
+// release unlocks and unmaps memory returned by alloc.
+func release(data []byte) {
+	_ = unix.Munlock(data) //nolint:errcheck // best effort
+	_ = unix.Munmap(data)  //nolint:errcheck // best effort
+}
@@ -7,9 +7,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/idelchi/gonc/internal/config"
 	"github.com/idelchi/gonc/internal/filter"
+	"github.com/idelchi/gonc/internal/report"
 	"github.com/idelchi/gonc/pkg/pathmatch"
 )
 
@@ -29,13 +31,24 @@ func RunCheck(cfg *config.Config) error {
 		return err
 	}
 
+	start := time.Now()
+	reporter := newReporter(cfg)
+
 	var failures int
 
-	failures += checkPatterns("include", includes, candidates, cfg.Quiet)
-	failures += checkPatterns("exclude", excludes, candidates, cfg.Quiet)
+	failures += checkPatterns(reporter, "include", includes, candidates)
+	failures += checkPatterns(reporter, "exclude", excludes, candidates)
+
+	reporter.Summary(report.Summary{
+		Command:   "check",
+		Scanned:   len(candidates),
+		Processed: len(includes) + len(excludes) - failures,
+		Errors:    failures,
+		Duration:  report.Milliseconds(time.Since(start)),
+	})
 
 	if failures > 0 {
-		return fmt.Errorf("%d pattern(s) matched no files", failures)
+		return fmt.Errorf("%w: %d pattern(s) matched no files", ErrCheck, failures)
 	}
 
 	return nil
@@ -124,36 +137,38 @@ func collectFiles(args []string) ([]string, error) {
 	return paths, nil
 }
 
-// checkPatterns tests each pattern individually against candidates.
+// checkPatterns tests each pattern individually against candidates and reports the result.
 // Returns the number of patterns that matched zero files.
-func checkPatterns(kind string, patterns, candidates []string, quiet bool) int {
+func checkPatterns(reporter *report.Reporter, kind string, patterns, candidates []string) int {
 	var failures int
 
 	for _, pattern := range patterns {
+		event := report.Pattern{Kind: kind, Pattern: pattern}
+
 		matcher, err := pathmatch.NewMatcher([]string{pattern})
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%s: %s — invalid pattern: %v\n", kind, pattern, err)
+			event.Error = fmt.Sprintf("invalid pattern: %v", err)
+
+			reporter.Pattern(event)
 
 			failures++
 
 			continue
 		}
 
-		var count int
-
 		for _, path := range candidates {
 			if matcher.MatchAny(path) {
-				count++
+				event.Matches++
 			}
 		}
 
-		if count == 0 {
-			fmt.Fprintf(os.Stderr, "%s: %s — 0 files (ERROR)\n", kind, pattern)
+		if event.Matches == 0 {
+			event.Error = report.NoMatches
 
 			failures++
-		} else if !quiet {
-			fmt.Fprintf(os.Stderr, "%s: %s — %d files\n", kind, pattern, count)
 		}
+
+		reporter.Pattern(event)
 	}
 
 	return failures
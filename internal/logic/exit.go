@@ -0,0 +1,80 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/encryption"
+	"github.com/idelchi/gonc/internal/fileutil"
+)
+
+// Exit codes, so that scripts can tell failure classes apart.
+const (
+	// ExitOK signals success.
+	ExitOK = 0
+	// ExitError signals a failure that fits no other class.
+	ExitError = 1
+	// ExitUsage signals invalid flags, arguments or configuration.
+	ExitUsage = 2
+	// ExitAuthentication signals a wrong key or tampered data.
+	ExitAuthentication = 3
+	// ExitEnvelope signals a malformed or truncated envelope.
+	ExitEnvelope = 4
+	// ExitIO signals a file that could not be read, written or verified.
+	ExitIO = 5
+	// ExitPartial signals that some files succeeded and others failed.
+	ExitPartial = 6
+	// ExitCheck signals a failed check: unmatched patterns, files out of sync, conflicts or a hash mismatch.
+	ExitCheck = 7
+	// ExitInterrupted signals a run stopped by SIGINT or SIGTERM.
+	ExitInterrupted = 130
+)
+
+var (
+	// ErrPartial marks runs in which some files were processed and others failed.
+	ErrPartial = errors.New("some files failed")
+
+	// ErrCheck marks checks that ran but did not pass.
+	ErrCheck = errors.New("check failed")
+)
+
+// ExitCode classifies an error returned by a command into its exit code.
+func ExitCode(err error) int {
+	var pathErr *fs.PathError
+
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, context.Canceled):
+		return ExitInterrupted
+	case errors.Is(err, config.ErrUsage):
+		return ExitUsage
+	case errors.Is(err, ErrPartial):
+		return ExitPartial
+	case errors.Is(err, ErrCheck):
+		return ExitCheck
+	case errors.Is(err, encryption.ErrVerification):
+		return ExitIO
+	case errors.Is(err, encryption.ErrAuthentication):
+		return ExitAuthentication
+	case errors.Is(err, encryption.ErrProcessing), errors.Is(err, io.ErrUnexpectedEOF):
+		return ExitEnvelope
+	case errors.Is(err, fileutil.ErrOutputExists), errors.As(err, &pathErr):
+		return ExitIO
+	default:
+		return ExitError
+	}
+}
+
+// partial marks the error of a run in which some files were processed as a partial success.
+func partial(err error, processed, errored int) error {
+	if err == nil || processed == 0 || errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	return fmt.Errorf("%w (%d of %d): %w", ErrPartial, errored, processed+errored, err)
+}
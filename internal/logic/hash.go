@@ -0,0 +1,115 @@
+package logic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/encryption"
+)
+
+// hmacScheme marks keyed hashes in redaction placeholders.
+const hmacScheme = "hmac-sha256"
+
+// minHashKeySize is the minimum length of a hash key in bytes.
+const minHashKeySize = 16
+
+// loadHashKey reads the key for keyed redaction hashes, or returns nil when none is configured.
+func loadHashKey(cfg *config.Config) ([]byte, error) {
+	hashKey, err := encryption.LoadHashKey(cfg.HashKey, cfg.Key.Insecure)
+	if err != nil {
+		return nil, fmt.Errorf("reading hash key: %w", err)
+	}
+
+	if hashKey != nil && len(hashKey) < minHashKeySize {
+		return nil, fmt.Errorf("%w: hash key must be at least %d bytes", config.ErrUsage, minHashKeySize)
+	}
+
+	return hashKey, nil
+}
+
+// hashFile computes the SHA-256 hex digest of a file.
+// With a key, the digest is an HMAC-SHA-256 prefixed with its scheme, so that
+// low-entropy originals cannot be recovered from the placeholder by brute force.
+func hashFile(filename string, hashKey []byte) (string, error) {
+	file, err := os.Open(filename) //nolint:gosec // filename is from resolved file list, not user input
+	if err != nil {
+		return "", fmt.Errorf("opening file: %w", err)
+	}
+	defer file.Close()
+
+	var hasher hash.Hash
+
+	if hashKey != nil {
+		hasher = hmac.New(sha256.New, hashKey)
+	} else {
+		hasher = sha256.New()
+	}
+
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+
+	digest := hex.EncodeToString(hasher.Sum(nil))
+
+	if hashKey != nil {
+		return hmacScheme + ":" + digest, nil
+	}
+
+	return digest, nil
+}
+
+// RunVerify checks that a plaintext file matches the hash in a redacted placeholder.
+// Keyed placeholders require the same hash key that was used when redacting.
+func RunVerify(cfg *config.Config, plaintext, redacted string) error {
+	data, err := os.ReadFile(redacted) //nolint:gosec // path is a user-supplied positional argument
+	if err != nil {
+		return fmt.Errorf("reading placeholder: %w", err)
+	}
+
+	placeholder := strings.TrimSpace(string(data))
+
+	idx := strings.LastIndex(placeholder, ":")
+	if idx < 0 {
+		return fmt.Errorf("%q does not contain a redaction hash", redacted)
+	}
+
+	stored, prefix := placeholder[idx+1:], placeholder[:idx]
+
+	hashKey, err := loadHashKey(cfg)
+	if err != nil {
+		return err
+	}
+
+	keyed := strings.HasSuffix(prefix, ":"+hmacScheme)
+
+	switch {
+	case keyed && hashKey == nil:
+		return fmt.Errorf("%w: %q holds a keyed hash, --hash-key or --hash-key-file is required", config.ErrUsage, redacted)
+	case !keyed:
+		hashKey = nil
+	}
+
+	computed, err := hashFile(plaintext, hashKey)
+	if err != nil {
+		return fmt.Errorf("hashing %q: %w", plaintext, err)
+	}
+
+	computed = computed[strings.LastIndex(computed, ":")+1:]
+
+	if !hmac.Equal([]byte(computed), []byte(stored)) {
+		return fmt.Errorf("%w: plaintext does not match the redacted placeholder", ErrCheck)
+	}
+
+	if !cfg.Quiet {
+		fmt.Printf("Verified %q matches %q\n", plaintext, redacted) //nolint:forbidigo
+	}
+
+	return nil
+}
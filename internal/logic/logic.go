@@ -2,27 +2,31 @@
 package logic
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
-	"github.com/dustin/go-humanize"
-
 	"github.com/idelchi/gonc/internal/config"
 	"github.com/idelchi/gonc/internal/encryption"
 	"github.com/idelchi/gonc/internal/fileutil"
 	"github.com/idelchi/gonc/internal/filter"
+	"github.com/idelchi/gonc/internal/placeholder"
+	"github.com/idelchi/gonc/internal/report"
+	"github.com/idelchi/gonc/internal/secrets"
+	"github.com/idelchi/gonc/internal/structured"
 )
 
 // Run is the main logic of the application.
-func Run(cfg *config.Config) error {
+func Run(ctx context.Context, cfg *config.Config) error {
 	scanned, excluded, start, done, err := preamble(cfg)
 	if done || err != nil {
 		return err
@@ -33,23 +37,61 @@ func Run(cfg *config.Config) error {
 		return fmt.Errorf("creating processor: %w", err)
 	}
 
-	processed, errored, totalSize, err := proc.ProcessFiles()
+	ctx, stop := interruptible(ctx)
+	defer stop()
+
+	reporter := newReporter(cfg)
+
+	processed, errored, totalSize, err := proc.ProcessFiles(ctx, reporter)
 
-	if cfg.Stats {
-		printStats(scanned, excluded, processed, errored, totalSize, time.Since(start))
+	summary := report.Summary{
+		Command:   command(cfg),
+		Scanned:   scanned,
+		Excluded:  excluded,
+		Processed: processed,
+		Errors:    errored,
+		Size:      totalSize,
+		Duration:  report.Milliseconds(time.Since(start)),
 	}
 
-	if err != nil {
+	if cfg.Verify {
+		failures := proc.VerifyFailures()
+		summary.VerifyFailures = &failures
+	}
+
+	reporter.Summary(summary)
+
+	if err := partial(err, processed, errored); err != nil {
 		return fmt.Errorf("running logic: %w", err)
 	}
 
 	return nil
 }
 
+// interruptible returns a context that is canceled on SIGINT or SIGTERM.
+// Once canceled, the handler is removed, so a second signal terminates immediately.
+func interruptible(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+
+	context.AfterFunc(ctx, stop)
+
+	return ctx, stop
+}
+
 // preamble resolves files and handles dry run. Returns done=true if dry run was executed.
 func preamble(cfg *config.Config) (int, int, time.Time, bool, error) {
 	start := time.Now()
 
+	if !cfg.Dry {
+		if err := recoverBatch(); err != nil {
+			return 0, 0, start, false, err
+		}
+	}
+
+	if err := checkOutputDir(cfg); err != nil {
+		return 0, 0, start, false, err
+	}
+
 	scanned, err := resolveFiles(cfg)
 	if err != nil {
 		return 0, 0, start, false, fmt.Errorf("resolving files: %w", err)
@@ -57,6 +99,10 @@ func preamble(cfg *config.Config) (int, int, time.Time, bool, error) {
 
 	excluded := scanned - len(cfg.Files)
 
+	if err := checkCollisions(cfg); err != nil {
+		return scanned, excluded, start, false, err
+	}
+
 	if cfg.Dry {
 		return scanned, excluded, start, true, dryRun(cfg, scanned, excluded, start)
 	}
@@ -64,16 +110,134 @@ func preamble(cfg *config.Config) (int, int, time.Time, bool, error) {
 	return scanned, excluded, start, false, nil
 }
 
+// recoverBatch finishes or rolls back an --atomic-batch commit that a previous run did not complete.
+func recoverBatch() error {
+	outcome, err := fileutil.Recover()
+	if err != nil {
+		return fmt.Errorf("recovering interrupted batch: %w", err)
+	}
+
+	if outcome != "" {
+		fmt.Fprintf(os.Stderr, "Recovered interrupted batch from %q: %s\n", fileutil.JournalName, outcome)
+	}
+
+	return nil
+}
+
+// checkOutputDir rejects an output directory that overlaps with one of the input paths,
+// so that outputs are never written into the source tree.
+func checkOutputDir(cfg *config.Config) error {
+	if cfg.OutputDir == "" {
+		if cfg.KeepExt {
+			return fmt.Errorf("%w: --keep-ext requires --output-dir", config.ErrUsage)
+		}
+
+		return nil
+	}
+
+	out, err := filepath.Abs(cfg.OutputDir)
+	if err != nil {
+		return fmt.Errorf("resolving output directory: %w", err)
+	}
+
+	if resolved, err := filepath.EvalSymlinks(out); err == nil {
+		out = resolved
+	}
+
+	for _, file := range cfg.Files {
+		in, err := filepath.Abs(file)
+		if err != nil {
+			return fmt.Errorf("resolving %q: %w", file, err)
+		}
+
+		if resolved, err := filepath.EvalSymlinks(in); err == nil {
+			in = resolved
+		}
+
+		if within(out, in) || within(in, out) {
+			return fmt.Errorf("%w: --output-dir %q overlaps with input %q", config.ErrUsage, cfg.OutputDir, file)
+		}
+	}
+
+	return nil
+}
+
+// checkCollisions rejects runs in which two inputs are written to the same output,
+// or in which an output would replace one of the inputs. Rewriting inputs in place needs --force.
+func checkCollisions(cfg *config.Config) error {
+	inputs := make(map[string]struct{}, len(cfg.Files))
+
+	for _, file := range cfg.Files {
+		inputs[filepath.Clean(file)] = struct{}{}
+	}
+
+	outputs := make(map[string]string, len(cfg.Files))
+
+	var errs []error
+
+	for _, file := range cfg.Files {
+		out := filepath.Clean(encryption.OutputPath(file, cfg))
+
+		if other, ok := outputs[out]; ok {
+			errs = append(errs, fmt.Errorf("%q and %q are both written to %q", other, file, out))
+
+			continue
+		}
+
+		outputs[out] = file
+
+		if _, ok := inputs[out]; ok && !cfg.Force {
+			errs = append(errs, fmt.Errorf("output %q of %q is also an input", out, file))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: output collisions:\n%w", config.ErrUsage, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// within reports whether path equals dir or lies below it.
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 // resolveFiles normalizes positional args, expands globs, and applies include/exclude filtering.
 // Returns the total number of files scanned before filtering.
 func resolveFiles(cfg *config.Config) (int, error) {
-	includes := append([]string{}, cfg.Include...)
-	excludes := append([]string{}, cfg.Exclude...)
+	includes, excludes, hasIncludes, err := filterPatterns(cfg)
+	if err != nil {
+		return 0, err
+	}
+
+	if (cfg.Decrypt || cfg.Rewrap) && !hasIncludes {
+		includes = append(includes, "*"+cfg.Suffixes.Encrypt)
+		hasIncludes = true
+	}
+
+	files, scanned, err := filter.Resolve(cfg.Files, includes, excludes, hasIncludes)
+	if err != nil {
+		return scanned, fmt.Errorf("filtering files: %w", err)
+	}
+
+	cfg.Files = files
+
+	return scanned, nil
+}
+
+// filterPatterns collects the inline and file-based include/exclude patterns.
+// hasIncludes reports whether include filtering was requested at all.
+func filterPatterns(cfg *config.Config) (includes, excludes []string, hasIncludes bool, err error) {
+	includes = append([]string{}, cfg.Include...)
+	excludes = append([]string{}, cfg.Exclude...)
 
 	if cfg.IncludeFrom != "" {
 		patterns, err := filter.LoadPatterns(cfg.IncludeFrom)
 		if err != nil {
-			return 0, fmt.Errorf("loading include patterns: %w", err)
+			return nil, nil, false, fmt.Errorf("loading include patterns: %w", err)
 		}
 
 		includes = append(includes, patterns...)
@@ -82,27 +246,41 @@ func resolveFiles(cfg *config.Config) (int, error) {
 	if cfg.ExcludeFrom != "" {
 		patterns, err := filter.LoadPatterns(cfg.ExcludeFrom)
 		if err != nil {
-			return 0, fmt.Errorf("loading exclude patterns: %w", err)
+			return nil, nil, false, fmt.Errorf("loading exclude patterns: %w", err)
 		}
 
 		excludes = append(excludes, patterns...)
 	}
 
-	hasIncludes := len(cfg.Include) > 0 || cfg.IncludeFrom != ""
+	return includes, excludes, len(cfg.Include) > 0 || cfg.IncludeFrom != "", nil
+}
 
-	if cfg.Decrypt && !hasIncludes {
-		includes = append(includes, "*"+cfg.Suffixes.Encrypt)
-		hasIncludes = true
+// entryFilter builds a filter for paths that are not walked from disk, such as archive entries.
+// defaultIncludes apply when no include patterns were given.
+func entryFilter(cfg *config.Config, defaultIncludes ...string) (*filter.Filter, bool, error) {
+	includes, excludes, hasIncludes, err := filterPatterns(cfg)
+	if err != nil {
+		return nil, false, err
 	}
 
-	files, scanned, err := filter.Resolve(cfg.Files, includes, excludes, hasIncludes)
-	if err != nil {
-		return scanned, fmt.Errorf("filtering files: %w", err)
+	if !hasIncludes && len(defaultIncludes) > 0 {
+		includes, hasIncludes = defaultIncludes, true
 	}
 
-	cfg.Files = files
+	for i := range includes {
+		includes[i] = strings.TrimPrefix(includes[i], "./")
+	}
 
-	return scanned, nil
+	for i := range excludes {
+		excludes[i] = strings.TrimPrefix(excludes[i], "./")
+	}
+
+	flt, err := filter.NewFilter(includes, excludes)
+	if err != nil {
+		return nil, false, fmt.Errorf("compiling patterns: %w", err)
+	}
+
+	return flt, hasIncludes, nil
 }
 
 // dryRun previews what would be processed without actually encrypting/decrypting.
@@ -111,57 +289,100 @@ func resolveFiles(cfg *config.Config) (int, error) {
 func dryRun(cfg *config.Config, scanned, excluded int, start time.Time) error {
 	var totalSize int64
 
-	processed := len(cfg.Files)
+	reporter := newReporter(cfg)
 
 	for _, file := range cfg.Files {
-		if !cfg.Quiet {
-			fmt.Printf("Processed %q -> %q\n", file, outputPath(file, cfg)) //nolint:forbidigo
-		}
+		event := report.File{Command: command(cfg), Input: file, Output: encryption.OutputPath(file, cfg), DryRun: true}
 
-		if cfg.Stats {
-			if info, err := os.Stat(file); err == nil {
-				totalSize += info.Size()
-			}
+		if info, err := os.Stat(file); err == nil {
+			event.InputSize = info.Size()
+			totalSize += info.Size()
 		}
-	}
 
-	if cfg.Stats {
-		printStats(scanned, excluded, processed, 0, totalSize, time.Since(start))
+		reporter.File(event)
 	}
 
+	reporter.Summary(report.Summary{
+		Command:   command(cfg),
+		Scanned:   scanned,
+		Excluded:  excluded,
+		Processed: len(cfg.Files),
+		Size:      totalSize,
+		Duration:  report.Milliseconds(time.Since(start)),
+		DryRun:    true,
+	})
+
 	return nil
 }
 
-func outputPath(filename string, cfg *config.Config) string {
-	ext := cfg.Suffixes.Encrypt
+// encryptedPath returns the path encrypt writes a plaintext file to.
+func encryptedPath(filename string, cfg *config.Config) string {
+	encrypt := *cfg
+	encrypt.Decrypt = false
 
-	if cfg.Decrypt {
-		filename = strings.TrimSuffix(filename, cfg.Suffixes.Encrypt)
-		ext = cfg.Suffixes.Decrypt
-	}
+	return encryption.OutputPath(filename, &encrypt)
+}
 
-	return filepath.Join(filepath.Dir(filename), filepath.Base(filename)+ext)
+// decryptedPath returns the path decrypt writes a ciphertext file to.
+func decryptedPath(filename string, cfg *config.Config) string {
+	decrypt := *cfg
+	decrypt.Decrypt = true
+
+	return encryption.OutputPath(filename, &decrypt)
 }
 
 // RunRedact replaces file contents with a fixed string, writing output to <file><encrypt-ext>.
 //
-//nolint:cyclop,gocognit // parallel processing pipeline with printer goroutine
-func RunRedact(cfg *config.Config) error {
+//nolint:cyclop,gocognit,funlen // parallel processing pipeline with printer goroutine
+func RunRedact(ctx context.Context, cfg *config.Config) error {
 	scanned, excluded, start, done, err := preamble(cfg)
 	if done || err != nil {
 		return err
 	}
 
+	red, err := newRedactor(cfg)
+	if err != nil {
+		return err
+	}
+
+	st, err := newStash(cfg)
+	if err != nil {
+		return err
+	}
+
+	var stashed []stashEntry
+
+	if st != nil {
+		cfg.Files = st.excludes(cfg.Files)
+	}
+
+	if cfg.AtomicBatch {
+		red.batch = fileutil.NewBatch(cfg.Delete, cfg.Durability())
+	}
+
+	ctx, stop := interruptible(ctx)
+	defer stop()
+
+	reporter := newReporter(cfg)
+	tracker := encryption.NewTracker(cfg)
+
+	reporter.Through(tracker.Writer)
+	tracker.Start()
+
 	type result struct {
-		input      string
-		output     string
-		outputSize int64
-		err        error
+		event   report.File
+		secrets int
+		stashed *stashEntry
 	}
 
 	results := make(chan result, len(cfg.Files))
 
-	group := errgroup.Group{}
+	group, scheduling := &errgroup.Group{}, ctx
+
+	if cfg.FailFast {
+		group, scheduling = errgroup.WithContext(ctx)
+	}
+
 	group.SetLimit(cfg.Parallel)
 
 	printed := make(chan struct{})
@@ -170,48 +391,78 @@ func RunRedact(cfg *config.Config) error {
 
 	var totalSize int64
 
+	var staged []result
+
 	go func() {
 		defer close(printed)
 
 		for res := range results {
-			if res.err != nil {
+			if res.event.Err != nil {
 				errored++
 
-				fmt.Fprintf(os.Stderr, "Error processing %q: %v\n", res.input, res.err)
-			} else {
-				processed++
+				reporter.File(res.event)
 
-				totalSize += res.outputSize
+				continue
+			}
 
-				if !cfg.Quiet {
-					fmt.Printf("Processed %q -> %q\n", res.input, res.output) //nolint:forbidigo
-				}
+			if res.stashed != nil {
+				stashed = append(stashed, *res.stashed)
 			}
 
-			if cfg.Delete && res.err == nil {
-				if err := os.Remove(res.input); err != nil {
-					fmt.Fprintf(os.Stderr, "Error deleting %q: %v\n", res.input, err)
-				} else if !cfg.Quiet {
-					fmt.Printf("Deleted %q\n", res.input) //nolint:forbidigo
+			if red.batch != nil {
+				staged = append(staged, res)
+
+				continue
+			}
+
+			processed++
+
+			totalSize += res.event.OutputSize
+
+			if cfg.Delete && res.event.Output != res.event.Input {
+				if err := os.Remove(res.event.Input); err != nil {
+					fmt.Fprintf(os.Stderr, "Error deleting %q: %v\n", res.event.Input, err)
+				} else {
+					res.event.Deleted = true
 				}
 			}
+
+			reporter.File(res.event)
 		}
 	}()
 
+	var started atomic.Int64
+
 	for _, file := range cfg.Files {
+		if scheduling.Err() != nil {
+			break
+		}
+
 		group.Go(func() error {
-			outPath := outputPath(file, cfg)
+			// A file may be queued while the first error cancels scheduling.
+			if scheduling.Err() != nil {
+				return nil
+			}
 
-			size, err := redactFile(file, outPath, cfg)
-			if err != nil {
-				results <- result{input: file, err: err}
+			started.Add(1)
+
+			res := result{event: red.event(file, encryption.OutputPath(file, cfg))}
+			start := time.Now()
+
+			res.stashed, res.secrets, res.event.Err = red.process(ctx, st, &res.event)
+			res.event.Duration = report.Milliseconds(time.Since(start))
 
-				return err
+			// Redaction mostly writes content without reading the input, so whole files are counted.
+			tracker.Add(res.event.InputSize)
+			tracker.FileDone()
+
+			if cfg.InPlaceSecrets {
+				res.event.Secrets = &res.secrets
 			}
 
-			results <- result{input: file, output: outPath, outputSize: size}
+			results <- res
 
-			return nil
+			return res.event.Err
 		})
 	}
 
@@ -221,38 +472,266 @@ func RunRedact(cfg *config.Config) error {
 
 	<-printed
 
-	if cfg.Stats {
-		printStats(scanned, excluded, processed, errored, totalSize, time.Since(start))
+	tracker.Stop()
+
+	skipped := len(cfg.Files) - int(started.Load())
+
+	reporter.Skip(skipped)
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = fmt.Errorf("interrupted: %w", ctxErr)
+	} else if skipped > 0 {
+		fmt.Fprintf(os.Stderr, "Stopped after the first error, %d files not processed\n", skipped)
 	}
 
-	if err != nil {
+	if red.batch != nil {
+		var deleted []string
+
+		if err == nil {
+			if deleted, err = red.batch.Commit(); err != nil {
+				errored += len(staged)
+				err = fmt.Errorf("committing batch: %w", err)
+			}
+		} else {
+			red.batch.Discard()
+
+			err = fmt.Errorf("batch rolled back: %w", err)
+		}
+
+		if err != nil {
+			if st != nil {
+				st.discard(stashed)
+			}
+
+			stashed = nil
+		}
+
+		removed := make(map[string]bool, len(deleted))
+
+		for _, file := range deleted {
+			removed[file] = true
+		}
+
+		for _, res := range staged {
+			if err != nil {
+				res.event.Err = fileutil.ErrRolledBack
+				reporter.File(res.event)
+
+				continue
+			}
+
+			processed++
+
+			totalSize += res.event.OutputSize
+
+			res.event.Deleted = removed[res.event.Input]
+			reporter.File(res.event)
+		}
+
+		for i := range stashed {
+			if stashed[i].SHA256, err = fileSHA256(stashed[i].Placeholder); err != nil {
+				err = fmt.Errorf("hashing placeholder: %w", err)
+
+				break
+			}
+		}
+	}
+
+	if st != nil {
+		if stashErr := st.record(stashed); stashErr != nil {
+			err = errors.Join(err, fmt.Errorf("recording stash: %w", stashErr))
+		}
+	}
+
+	reporter.Summary(report.Summary{
+		Command:   "redact",
+		Scanned:   scanned,
+		Excluded:  excluded,
+		Processed: processed,
+		Errors:    errored,
+		Size:      totalSize,
+		Duration:  report.Milliseconds(time.Since(start)),
+		Shape:     cfg.Shape,
+	})
+
+	if err := partial(err, processed, errored); err != nil {
 		return fmt.Errorf("redacting files: %w", err)
 	}
 
 	return nil
 }
 
-// redactFile writes the content string to a temp file and atomically renames it to outPath.
-func redactFile(filename, outPath string, cfg *config.Config) (size int64, err error) {
-	tc, err := fileutil.NewTempContext(filename, outPath)
+// redactor holds the state shared by every file of a redact run.
+type redactor struct {
+	// cfg is the run configuration
+	cfg *config.Config
+
+	// scanner finds secrets for --in-place-secrets, nil otherwise
+	scanner *secrets.Scanner
+
+	// hashKey keys the placeholder hash, nil for plain SHA-256
+	hashKey []byte
+
+	// templates choose the content per file
+	templates *placeholder.Templates
+
+	// batch stages outputs for an all-or-nothing commit, nil outside of --atomic-batch
+	batch *fileutil.Batch
+}
+
+// newRedactor validates the redact options and loads the rules and keys they reference.
+func newRedactor(cfg *config.Config) (*redactor, error) {
+	if cfg.InPlaceSecrets && (cfg.Structured || cfg.Hash) {
+		return nil, fmt.Errorf("%w: --in-place-secrets cannot be combined with --structured or --hash", config.ErrUsage)
+	}
+
+	scanner, err := newSecretScanner(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	hashKey, err := loadHashKey(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if hashKey != nil {
+		cfg.Hash = true
+	}
+
+	if cfg.Shape == "" {
+		cfg.Shape = shapeContent
+	}
+
+	if cfg.Shape != shapeContent && (cfg.Structured || cfg.InPlaceSecrets || cfg.Hash) {
+		return nil, fmt.Errorf("%w: --shape %s cannot be combined with --structured, --in-place-secrets or --hash",
+			config.ErrUsage, cfg.Shape)
+	}
+
+	templates, err := placeholder.New(nil, cfg.Content)
+
+	if cfg.ContentRules != "" {
+		templates, err = placeholder.Load(cfg.ContentRules, cfg.Content)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("loading content rules: %w", err)
+	}
+
+	return &redactor{cfg: cfg, scanner: scanner, hashKey: hashKey, templates: templates}, nil
+}
+
+// process stashes the original if requested and redacts the file of event, recording the output in it.
+// An original stashed for a file that then fails is removed again.
+func (r *redactor) process(
+	ctx context.Context,
+	st *stash,
+	event *report.File,
+) (entry *stashEntry, found int, err error) {
+	if st != nil {
+		rel, err := st.store(event.Input)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		entry = &stashEntry{Original: event.Input, Placeholder: event.Output, Stash: rel}
+	}
+
+	found, err = r.redactFile(ctx, event)
+	if err != nil {
+		if entry != nil {
+			st.discard([]stashEntry{*entry})
+		}
+
+		return nil, 0, err
+	}
+
+	// Staged placeholders are hashed once the batch is committed.
+	if entry != nil && r.batch == nil {
+		if entry.SHA256, err = fileSHA256(event.Output); err != nil {
+			return nil, 0, fmt.Errorf("hashing placeholder: %w", err)
+		}
+	}
+
+	return entry, found, nil
+}
+
+// event starts the report of a redacted file.
+func (r *redactor) event(file, outPath string) report.File {
+	event := report.File{Command: "redact", Input: file, Output: outPath, Mode: r.mode()}
+
+	if info, err := os.Stat(file); err == nil {
+		event.InputSize = info.Size()
+	}
+
+	return event
+}
+
+// mode names how files are redacted.
+func (r *redactor) mode() string {
+	switch {
+	case r.cfg.Structured:
+		return "structured"
+	case r.scanner != nil:
+		return "in-place-secrets"
+	default:
+		return r.cfg.Shape
+	}
+}
+
+// redactFile writes the redacted content to a temp file and atomically renames it to outPath.
+// With a scanner, only the detected secret spans are replaced and their count is returned.
+// The output size and whether it was unchanged are recorded in event.
+//
+//nolint:cyclop,funlen // sequential atomic-write steps
+func (r *redactor) redactFile(ctx context.Context, event *report.File) (found int, err error) {
+	filename, outPath := event.Input, event.Output
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	tc, err := fileutil.NewTempContext(filename, outPath, r.cfg.Durability())
 	if err != nil {
 		return 0, fmt.Errorf("preparing atomic write: %w", err)
 	}
 
 	defer tc.CleanupOnError(&err)
 
-	content := cfg.Content
+	content, err := r.templates.Content(filename)
+	if err != nil {
+		return 0, fmt.Errorf("choosing content: %w", err)
+	}
 
-	if cfg.Hash {
-		hash, hashErr := hashFile(filename)
+	if r.cfg.Hash {
+		hash, hashErr := hashFile(filename, r.hashKey)
 		if hashErr != nil {
 			return 0, fmt.Errorf("hashing file: %w", hashErr)
 		}
 
-		content = cfg.Content + ":" + hash
+		content += ":" + hash
 	}
 
-	if _, err = tc.TmpFile.WriteString(content); err != nil {
+	data := []byte(content)
+
+	switch {
+	case r.cfg.Structured:
+		if data, err = redactStructured(filename, content); err != nil {
+			return 0, err
+		}
+	case r.scanner != nil:
+		if data, found, err = redactSecrets(filename, content, r.scanner); err != nil {
+			return 0, err
+		}
+	case r.cfg.Shape != shapeContent:
+		if err = writeShaped(tc.TmpFile, filename, content, r.cfg.Shape); err != nil {
+			return 0, err
+		}
+
+		data = nil
+	}
+
+	if _, err = tc.TmpFile.Write(data); err != nil {
 		return 0, fmt.Errorf("writing content: %w", err)
 	}
 
@@ -268,46 +747,121 @@ func redactFile(filename, outPath string, cfg *config.Config) (size int64, err e
 		return 0, fmt.Errorf("setting file permissions: %w", err)
 	}
 
-	if err := tc.TmpFile.Close(); err != nil {
-		return 0, fmt.Errorf("closing temporary file: %w", err)
+	if err := tc.Close(r.cfg.Durability()); err != nil {
+		return 0, err
 	}
 
-	if err := os.Rename(tc.TmpName, outPath); err != nil {
-		return 0, fmt.Errorf("renaming output file: %w", err)
+	// Content is built in memory, so an interrupt is honored before the output is moved into place.
+	if err := ctx.Err(); err != nil {
+		return 0, err
 	}
 
-	size, err = fileutil.FinalizeOutput(outPath, cfg.PreserveTimestamps, tc.SrcInfo.ModTime())
+	overwrite := fileutil.Overwrite{Force: r.cfg.Force, Backup: r.cfg.Backup}
+
+	if r.batch != nil {
+		event.OutputSize, err = fileutil.FinalizeOutput(tc.TmpName, r.cfg.PreserveTimestamps, tc.SrcInfo.ModTime())
+		if err != nil {
+			return 0, fmt.Errorf("finalizing output: %w", err)
+		}
+
+		existing, err := r.batch.Stage(filename, outPath, tc.TmpName, overwrite, nil)
+		event.Unchanged = existing == fileutil.Unchanged
+
+		return found, err
+	}
+
+	existing, err := overwrite.Guard(outPath, tc.TmpName, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	event.Unchanged = existing == fileutil.Unchanged
+
+	if err := tc.Rename(outPath, r.cfg.Durability()); err != nil {
+		return 0, err
+	}
+
+	event.OutputSize, err = fileutil.FinalizeOutput(outPath, r.cfg.PreserveTimestamps, tc.SrcInfo.ModTime())
 	if err != nil {
 		return 0, fmt.Errorf("finalizing output: %w", err)
 	}
 
-	return size, nil
+	return found, nil
 }
 
-// hashFile computes the SHA-256 hex digest of a file.
-func hashFile(filename string) (string, error) {
-	file, err := os.Open(filename) //nolint:gosec // filename is from resolved file list, not user input
+// newSecretScanner builds the scanner for --in-place-secrets, or returns nil when it is not set.
+func newSecretScanner(cfg *config.Config) (*secrets.Scanner, error) {
+	if !cfg.InPlaceSecrets {
+		return nil, nil //nolint:nilnil // no scanner is a valid state
+	}
+
+	var rules []secrets.Rule
+
+	if cfg.SecretRules != "" {
+		loaded, err := secrets.LoadRules(cfg.SecretRules)
+		if err != nil {
+			return nil, fmt.Errorf("loading secret rules: %w", err)
+		}
+
+		rules = loaded
+	}
+
+	scanner, err := secrets.NewScanner(rules, !cfg.NoDetectors)
 	if err != nil {
-		return "", fmt.Errorf("opening file: %w", err)
+		return nil, fmt.Errorf("compiling secret rules: %w", err)
 	}
-	defer file.Close()
 
-	hasher := sha256.New()
+	return scanner, nil
+}
 
-	if _, err := io.Copy(hasher, file); err != nil {
-		return "", fmt.Errorf("reading file: %w", err)
+// redactSecrets replaces the detected secret spans of a file with content.
+func redactSecrets(filename, content string, scanner *secrets.Scanner) ([]byte, int, error) {
+	data, err := os.ReadFile(filename) //nolint:gosec // filename is from resolved file list, not user input
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading file: %w", err)
 	}
 
-	return hex.EncodeToString(hasher.Sum(nil)), nil
+	out, findings := scanner.Redact(data, content)
+
+	return out, len(findings), nil
 }
 
-func printStats(scanned, excluded, processed, errored int, totalSize int64, duration time.Duration) {
-	fmt.Fprintf(os.Stderr, "\nStats\n")
-	fmt.Fprintf(os.Stderr, "  Scanned:   %d\n", scanned)
-	fmt.Fprintf(os.Stderr, "  Excluded:  %d\n", excluded)
-	fmt.Fprintf(os.Stderr, "  Processed: %d\n", processed)
-	fmt.Fprintf(os.Stderr, "  Errors:    %d\n", errored)
-	//nolint:gosec // totalSize is always non-negative (sum of file sizes)
-	fmt.Fprintf(os.Stderr, "  Size:      %s\n", humanize.IBytes(uint64(max(0, totalSize))))
-	fmt.Fprintf(os.Stderr, "  Duration:  %s\n", duration.Round(time.Millisecond))
+// redactStructured replaces every leaf value of a structured file with a typed placeholder:
+// strings become content, numbers 0 and booleans false. Keys, nesting and nulls are kept.
+// Files in an unknown format fall back to whole-file redaction.
+func redactStructured(filename, content string) ([]byte, error) {
+	data, err := os.ReadFile(filename) //nolint:gosec // filename is from resolved file list, not user input
+	if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+
+	doc, err := structured.Parse(filename, data)
+
+	switch {
+	case errors.Is(err, structured.ErrUnsupported):
+		return []byte(content), nil
+	case err != nil:
+		return nil, fmt.Errorf("parsing document: %w", err)
+	}
+
+	edits := make([]structured.Edit, 0, len(doc.Leaves()))
+
+	for _, leaf := range doc.Leaves() {
+		switch leaf.Kind {
+		case structured.Number:
+			edits = append(edits, structured.Edit{Leaf: leaf, Kind: structured.Number, Value: "0"})
+		case structured.Bool:
+			edits = append(edits, structured.Edit{Leaf: leaf, Kind: structured.Bool, Value: "false"})
+		case structured.String, structured.Raw:
+			edits = append(edits, structured.Edit{Leaf: leaf, Kind: structured.String, Value: content})
+		case structured.Null:
+		}
+	}
+
+	out, err := doc.Apply(edits)
+	if err != nil {
+		return nil, fmt.Errorf("rewriting document: %w", err)
+	}
+
+	return out, nil
 }
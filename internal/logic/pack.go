@@ -0,0 +1,509 @@
+package logic
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/encryption"
+	"github.com/idelchi/gonc/internal/fileutil"
+	"github.com/idelchi/gonc/internal/report"
+)
+
+// errUnsafePath is returned for archive entries that would be written outside the destination.
+var errUnsafePath = errors.New("unsafe path in archive")
+
+// RunPack streams the resolved files into a tar archive and encrypts it into a single envelope.
+// Parent directories of the files are archived too; symlinks are stored as links.
+//
+//nolint:funlen // sequential archive-and-rename steps
+func RunPack(cfg *config.Config, archive string) (err error) {
+	start := time.Now()
+
+	scanned, err := resolveFiles(cfg)
+	if err != nil {
+		return fmt.Errorf("resolving files: %w", err)
+	}
+
+	archiveClean := filepath.Clean(archive)
+
+	files := cfg.Files[:0]
+
+	for _, file := range cfg.Files {
+		if file != archiveClean {
+			files = append(files, file)
+		}
+	}
+
+	cfg.Files = files
+	excluded := scanned - len(cfg.Files)
+
+	if cfg.Dry {
+		for _, file := range cfg.Files {
+			if !cfg.Quiet {
+				fmt.Printf("Packed %q -> %q\n", file, archive) //nolint:forbidigo
+			}
+		}
+
+		return nil
+	}
+
+	proc, err := encryption.NewProcessor(cfg)
+	if err != nil {
+		return fmt.Errorf("creating processor: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(archive), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	defer func() {
+		tmp.Close() //nolint:gosec // best-effort cleanup
+
+		if err != nil {
+			os.Remove(tmp.Name()) //nolint:gosec // best-effort cleanup
+		}
+	}()
+
+	reader, writer := io.Pipe()
+
+	go func() {
+		writer.CloseWithError(writeTar(writer, cfg))
+	}()
+
+	if err = proc.Encrypt(reader, tmp, false); err != nil {
+		reader.CloseWithError(err)
+
+		return fmt.Errorf("packing %q: %w", archive, err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	overwrite := fileutil.Overwrite{Force: cfg.Force, Backup: cfg.Backup}
+
+	if _, err = overwrite.Guard(archive, tmp.Name(), nil); err != nil {
+		return err //nolint:wrapcheck // already names the archive
+	}
+
+	if err = os.Rename(tmp.Name(), archive); err != nil {
+		return fmt.Errorf("renaming archive: %w", err)
+	}
+
+	info, err := os.Stat(archive)
+	if err != nil {
+		return fmt.Errorf("stat archive %q: %w", archive, err)
+	}
+
+	if !cfg.Quiet {
+		fmt.Printf("Packed %d file(s) -> %q\n", len(cfg.Files), archive) //nolint:forbidigo
+	}
+
+	if cfg.Stats {
+		report.WriteStats(os.Stderr, report.Summary{
+			Scanned:   scanned,
+			Excluded:  excluded,
+			Processed: len(cfg.Files),
+			Size:      info.Size(),
+			Duration:  report.Milliseconds(time.Since(start)),
+		})
+	}
+
+	return nil
+}
+
+// writeTar writes the resolved files and their parent directories to a tar stream.
+func writeTar(writer io.Writer, cfg *config.Config) error {
+	archive := tar.NewWriter(writer)
+
+	written := make(map[string]struct{})
+
+	for _, file := range cfg.Files {
+		for _, dir := range parentDirs(file) {
+			if _, ok := written[dir]; ok {
+				continue
+			}
+
+			written[dir] = struct{}{}
+
+			if err := writeTarEntry(archive, dir); err != nil {
+				return err
+			}
+		}
+
+		if err := writeTarEntry(archive, file); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("closing archive: %w", err)
+	}
+
+	return nil
+}
+
+// parentDirs returns the ancestors of a relative file path, outermost first.
+func parentDirs(file string) []string {
+	var dirs []string
+
+	for dir := filepath.Dir(file); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+	}
+
+	sort.Slice(dirs, func(i, j int) bool { return len(dirs[i]) < len(dirs[j]) })
+
+	return dirs
+}
+
+// writeTarEntry writes a header for name, followed by its content for regular files.
+func writeTarEntry(archive *tar.Writer, name string) error {
+	info, err := os.Lstat(name)
+	if err != nil {
+		return fmt.Errorf("stat %q: %w", name, err)
+	}
+
+	var link string
+
+	if info.Mode()&fs.ModeSymlink != 0 {
+		if link, err = os.Readlink(name); err != nil {
+			return fmt.Errorf("reading link %q: %w", name, err)
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return fmt.Errorf("creating header for %q: %w", name, err)
+	}
+
+	header.Name = filepath.ToSlash(name)
+	header.Uname, header.Gname = "", ""
+
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	if err := archive.WriteHeader(header); err != nil {
+		return fmt.Errorf("writing header for %q: %w", name, err)
+	}
+
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	file, err := os.Open(name) //nolint:gosec // name is from resolved file list, not user input
+	if err != nil {
+		return fmt.Errorf("opening %q: %w", name, err)
+	}
+
+	defer file.Close()
+
+	if _, err := io.Copy(archive, file); err != nil {
+		return fmt.Errorf("archiving %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// RunUnpack authenticates an archive written by pack and extracts it below dest.
+// The whole envelope is decrypted and authenticated before anything is extracted.
+// Entries that would escape dest, or pass through a symlink, are rejected.
+// Include/exclude patterns select which entries are extracted.
+//
+//nolint:cyclop,funlen // staged decrypt, extract and finalize
+func RunUnpack(cfg *config.Config, archive, dest string) error {
+	flt, hasIncludes, err := entryFilter(cfg)
+	if err != nil {
+		return err
+	}
+
+	cfg.Decrypt = true
+
+	proc, err := encryption.NewProcessor(cfg)
+	if err != nil {
+		return fmt.Errorf("creating processor: %w", err)
+	}
+
+	const ownerOnly = 0o700
+
+	// created holds the directories made by this run; only those get the modes and times from the archive.
+	created := make(map[string]bool)
+
+	if err := mkdirTracked(dest, ownerOnly, created); err != nil {
+		return fmt.Errorf("creating destination: %w", err)
+	}
+
+	staged, err := stageArchive(proc, archive, dest)
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(staged) //nolint:errcheck // best-effort cleanup
+
+	file, err := os.Open(staged) //nolint:gosec // staged is our own temporary file
+	if err != nil {
+		return fmt.Errorf("opening staged archive: %w", err)
+	}
+
+	defer file.Close()
+
+	reader := tar.NewReader(file)
+
+	var (
+		links     []*tar.Header
+		dirs      []*tar.Header
+		extracted int
+	)
+
+	for {
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("reading archive: %w", err)
+		}
+
+		name, err := safeEntryName(header.Name)
+		if err != nil {
+			return err
+		}
+
+		if header.Typeflag == tar.TypeDir {
+			dirs = append(dirs, header)
+
+			continue
+		}
+
+		if !flt.Match(name, hasIncludes) {
+			continue
+		}
+
+		target := filepath.Join(dest, filepath.FromSlash(name))
+
+		if cfg.Dry {
+			if !cfg.Quiet {
+				fmt.Printf("Extracted %q\n", target) //nolint:forbidigo
+			}
+
+			continue
+		}
+
+		if err := checkNoSymlinks(dest, name); err != nil {
+			return err
+		}
+
+		if err := mkdirTracked(filepath.Dir(target), ownerOnly, created); err != nil {
+			return fmt.Errorf("creating directory for %q: %w", target, err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			links = append(links, header)
+		case tar.TypeReg:
+			if err := extractFile(reader, header, target); err != nil {
+				return err
+			}
+
+			if !cfg.Quiet {
+				fmt.Printf("Extracted %q\n", target) //nolint:forbidigo
+			}
+		default:
+			fmt.Fprintf(os.Stderr, "Skipping %q: unsupported entry type\n", header.Name)
+
+			continue
+		}
+
+		extracted++
+	}
+
+	// Symlinks are created last so that no entry can be written through one.
+	for _, header := range links {
+		name, _ := safeEntryName(header.Name) //nolint:errcheck // validated above
+
+		target := filepath.Join(dest, filepath.FromSlash(name))
+
+		if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("replacing %q: %w", target, err)
+		}
+
+		if err := os.Symlink(header.Linkname, target); err != nil {
+			return fmt.Errorf("creating symlink %q: %w", target, err)
+		}
+
+		if !cfg.Quiet {
+			fmt.Printf("Extracted %q -> %q\n", target, header.Linkname) //nolint:forbidigo
+		}
+	}
+
+	// Directory modes and times are applied last, deepest first, to the directories this run created.
+	// Directories that already existed keep their own.
+	for i := len(dirs) - 1; i >= 0 && !cfg.Dry; i-- {
+		name, _ := safeEntryName(dirs[i].Name) //nolint:errcheck // validated above
+
+		target := filepath.Join(dest, filepath.FromSlash(name))
+
+		if !created[target] {
+			continue
+		}
+
+		if err := os.Chmod(target, dirs[i].FileInfo().Mode().Perm()); err != nil {
+			return fmt.Errorf("setting permissions of %q: %w", target, err)
+		}
+
+		if err := os.Chtimes(target, dirs[i].ModTime, dirs[i].ModTime); err != nil {
+			return fmt.Errorf("setting times of %q: %w", target, err)
+		}
+	}
+
+	if extracted == 0 && !cfg.Dry {
+		return errors.New("no archive entries matched the provided patterns")
+	}
+
+	return nil
+}
+
+// mkdirTracked creates dir and its missing parents, recording each directory it creates in created.
+func mkdirTracked(dir string, perm fs.FileMode, created map[string]bool) error {
+	var missing []string
+
+	for current := filepath.Clean(dir); ; current = filepath.Dir(current) {
+		if _, err := os.Lstat(current); err == nil {
+			break
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("stat %q: %w", current, err)
+		}
+
+		missing = append(missing, current)
+
+		if filepath.Dir(current) == current {
+			break
+		}
+	}
+
+	if err := os.MkdirAll(dir, perm); err != nil {
+		return fmt.Errorf("creating %q: %w", dir, err)
+	}
+
+	for _, path := range missing {
+		created[path] = true
+	}
+
+	return nil
+}
+
+// stageArchive decrypts the archive into a temporary file inside dest and returns its name.
+func stageArchive(proc *encryption.Processor, archive, dest string) (name string, err error) {
+	src, err := os.Open(archive) //nolint:gosec // archive is user-supplied input
+	if err != nil {
+		return "", fmt.Errorf("opening archive: %w", err)
+	}
+
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(dest, ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	defer func() {
+		tmp.Close() //nolint:gosec // best-effort cleanup
+
+		if err != nil {
+			os.Remove(tmp.Name()) //nolint:gosec // best-effort cleanup
+		}
+	}()
+
+	if _, err = proc.Decrypt(src, tmp); err != nil {
+		return "", fmt.Errorf("decrypting archive: %w", err)
+	}
+
+	return tmp.Name(), nil
+}
+
+// extractFile writes a regular entry atomically, restoring its mode and modification time.
+func extractFile(reader io.Reader, header *tar.Header, target string) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	defer func() {
+		tmp.Close() //nolint:gosec // best-effort cleanup
+
+		if err != nil {
+			os.Remove(tmp.Name()) //nolint:gosec // best-effort cleanup
+		}
+	}()
+
+	if _, err = io.Copy(tmp, reader); err != nil { //nolint:gosec // size is bounded by the authenticated archive
+		return fmt.Errorf("extracting %q: %w", target, err)
+	}
+
+	if err = tmp.Chmod(header.FileInfo().Mode().Perm()); err != nil {
+		return fmt.Errorf("setting permissions of %q: %w", target, err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	if err = os.Chtimes(tmp.Name(), header.ModTime, header.ModTime); err != nil {
+		return fmt.Errorf("setting times of %q: %w", target, err)
+	}
+
+	if err = os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("renaming %q: %w", target, err)
+	}
+
+	return nil
+}
+
+// safeEntryName cleans an archive entry name and rejects absolute names and names that leave the destination.
+func safeEntryName(name string) (string, error) {
+	clean := path.Clean(strings.TrimSuffix(name, "/"))
+
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") || filepath.VolumeName(clean) != "" {
+		return "", fmt.Errorf("%w: %q", errUnsafePath, name)
+	}
+
+	return clean, nil
+}
+
+// checkNoSymlinks rejects entries whose parent directories below dest are symlinks.
+func checkNoSymlinks(dest, name string) error {
+	current := dest
+
+	for _, part := range strings.Split(path.Dir(name), "/") {
+		if part == "." {
+			break
+		}
+
+		current = filepath.Join(current, part)
+
+		info, err := os.Lstat(current)
+
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			return nil
+		case err != nil:
+			return fmt.Errorf("stat %q: %w", current, err)
+		case info.Mode()&fs.ModeSymlink != 0:
+			return fmt.Errorf("%w: %q passes through symlink %q", errUnsafePath, name, current)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/encryption"
+	"github.com/idelchi/gonc/internal/fileutil"
+	"github.com/idelchi/gonc/internal/report"
+)
+
+// newReporter creates the reporter for the configured --output format.
+func newReporter(cfg *config.Config) *report.Reporter {
+	return report.New(cfg.Output, cfg.Quiet, cfg.Stats, errorClass)
+}
+
+// command names the file-processing command a configuration runs.
+func command(cfg *config.Config) string {
+	switch {
+	case cfg.Redact:
+		return "redact"
+	case cfg.Rewrap:
+		return "rewrap"
+	case cfg.Decrypt:
+		return "decrypt"
+	default:
+		return "encrypt"
+	}
+}
+
+// errorClass groups a file error by its cause for machine-readable output.
+func errorClass(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return report.ClassCanceled
+	case errors.Is(err, fileutil.ErrRolledBack):
+		return report.ClassRolledBack
+	case errors.Is(err, config.ErrUsage):
+		return "usage"
+	case errors.Is(err, encryption.ErrVerification):
+		return "verification"
+	case errors.Is(err, encryption.ErrAuthentication):
+		return "authentication"
+	case errors.Is(err, encryption.ErrProcessing), errors.Is(err, io.ErrUnexpectedEOF):
+		return "envelope"
+	case errors.Is(err, fileutil.ErrOutputExists):
+		return "output_exists"
+	case errors.Is(err, fs.ErrNotExist):
+		return "not_found"
+	case errors.Is(err, fs.ErrPermission):
+		return "permission"
+	default:
+		return "error"
+	}
+}
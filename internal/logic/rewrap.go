@@ -0,0 +1,105 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/encryption"
+	"github.com/idelchi/gonc/internal/report"
+)
+
+// RunRewrap moves the resolved envelopes from the key-encryption key of --from-provider
+// to that of --key-provider, rewriting only their headers.
+func RunRewrap(ctx context.Context, cfg *config.Config) error {
+	start := time.Now()
+
+	rewrapper, err := encryption.NewRewrapper(cfg)
+	if err != nil {
+		return err
+	}
+
+	scanned, err := resolveFiles(cfg)
+	if err != nil {
+		return fmt.Errorf("resolving files: %w", err)
+	}
+
+	ctx, stop := interruptible(ctx)
+	defer stop()
+
+	reporter := newReporter(cfg)
+
+	results := make(chan report.File, len(cfg.Files))
+
+	group := errgroup.Group{}
+	group.SetLimit(cfg.Parallel)
+
+	for _, file := range cfg.Files {
+		group.Go(func() error {
+			event := report.File{Command: command(cfg), Input: file, Output: file}
+
+			if err := ctx.Err(); err != nil {
+				event.Err = fmt.Errorf("interrupted: %w", err)
+				results <- event
+
+				return event.Err
+			}
+
+			if info, err := os.Stat(file); err == nil {
+				event.InputSize = info.Size()
+			}
+
+			began := time.Now()
+
+			event.Unchanged, event.OutputSize, event.Err = rewrapper.RewrapFile(file)
+			event.Duration = report.Milliseconds(time.Since(began))
+
+			results <- event
+
+			return event.Err
+		})
+	}
+
+	err = group.Wait()
+
+	close(results)
+
+	var processed, errored int
+
+	var totalSize int64
+
+	for event := range results {
+		if event.Err != nil {
+			errored++
+		} else {
+			processed++
+			totalSize += event.OutputSize
+		}
+
+		reporter.File(event)
+	}
+
+	reporter.Summary(report.Summary{
+		Command:   command(cfg),
+		Scanned:   scanned,
+		Excluded:  scanned - len(cfg.Files),
+		Processed: processed,
+		Errors:    errored,
+		Size:      totalSize,
+		Duration:  report.Milliseconds(time.Since(start)),
+	})
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		err = fmt.Errorf("interrupted: %w", ctxErr)
+	}
+
+	if err := partial(err, processed, errored); err != nil {
+		return fmt.Errorf("rewrapping: %w", err)
+	}
+
+	return nil
+}
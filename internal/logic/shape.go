@@ -0,0 +1,125 @@
+package logic
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Redaction shapes select how much of the original file layout a placeholder keeps.
+const (
+	// shapeContent writes the content as is.
+	shapeContent = "content"
+	// shapeSize repeats the content up to the original byte length.
+	shapeSize = "size"
+	// shapeRandom writes random bytes of the original byte length.
+	shapeRandom = "random"
+	// shapeLines keeps the line count and line lengths, masking each line with the content.
+	shapeLines = "lines"
+)
+
+// errEmptyFill is returned when a size or line shape has no content to fill with.
+var errEmptyFill = errors.New("content must not be empty to fill a shape")
+
+// writeShaped writes a placeholder with the configured shape of filename to writer.
+func writeShaped(writer io.Writer, filename, content, shape string) error {
+	file, err := os.Open(filename) //nolint:gosec // filename is from resolved file list, not user input
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("getting file info: %w", err)
+	}
+
+	if shape != shapeRandom && content == "" {
+		return errEmptyFill
+	}
+
+	switch shape {
+	case shapeRandom:
+		if _, err := io.CopyN(writer, rand.Reader, info.Size()); err != nil {
+			return fmt.Errorf("writing random bytes: %w", err)
+		}
+	case shapeSize:
+		if err := fill(writer, content, info.Size()); err != nil {
+			return err
+		}
+	case shapeLines:
+		buffered := bufio.NewWriter(writer)
+
+		if err := maskLines(buffered, file, content); err != nil {
+			return err
+		}
+
+		if err := buffered.Flush(); err != nil {
+			return fmt.Errorf("writing content: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown shape %q", shape)
+	}
+
+	return nil
+}
+
+// fill writes content repeatedly to writer, truncated to exactly size bytes.
+func fill(writer io.Writer, content string, size int64) error {
+	const block = 32 * 1024
+
+	pattern := []byte(content)
+	for len(pattern) < block {
+		pattern = append(pattern, content...)
+	}
+
+	for size > 0 {
+		chunk := pattern[:min(int64(len(pattern)), size)]
+
+		if _, err := writer.Write(chunk); err != nil {
+			return fmt.Errorf("writing content: %w", err)
+		}
+
+		size -= int64(len(chunk))
+	}
+
+	return nil
+}
+
+// maskLines copies the line endings of reader to writer and fills every line with content.
+func maskLines(writer io.Writer, reader io.Reader, content string) error {
+	buffered := bufio.NewReader(reader)
+
+	var length int64
+
+	for {
+		char, err := buffered.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return fill(writer, content, length)
+		}
+
+		if err != nil {
+			return fmt.Errorf("reading file: %w", err)
+		}
+
+		if char != '\n' && char != '\r' {
+			length++
+
+			continue
+		}
+
+		if err := fill(writer, content, length); err != nil {
+			return err
+		}
+
+		length = 0
+
+		if _, err := writer.Write([]byte{char}); err != nil {
+			return fmt.Errorf("writing content: %w", err)
+		}
+	}
+}
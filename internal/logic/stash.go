@@ -0,0 +1,428 @@
+package logic
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/encryption"
+	"github.com/idelchi/gonc/internal/fileutil"
+)
+
+const (
+	// stashManifest is the name of the encrypted manifest inside a stash directory.
+	stashManifest = "manifest.json.gonc"
+	// stashExt is appended to stashed originals.
+	stashExt = ".gonc"
+)
+
+// errPlaceholderModified is returned when a placeholder changed after it was written by redact.
+var errPlaceholderModified = errors.New("placeholder was modified after redaction")
+
+// stashEntry records where the original of a redacted file was stashed.
+type stashEntry struct {
+	// Original file path
+	Original string `json:"original"`
+
+	// Redacted placeholder path
+	Placeholder string `json:"placeholder"`
+
+	// Stashed ciphertext path, relative to the stash directory
+	Stash string `json:"stash"`
+
+	// SHA-256 of the placeholder as written by redact
+	SHA256 string `json:"sha256"`
+}
+
+// stash stores encrypted originals of redacted files in a directory.
+type stash struct {
+	// dir is the stash directory
+	dir string
+
+	// encrypter stores originals into the stash
+	encrypter *encryption.Processor
+
+	// decrypter reads originals and the manifest back
+	decrypter *encryption.Processor
+
+	// durable syncs new stash directories before originals are removed
+	durable bool
+}
+
+// newStash prepares the stash configured with --stash, or returns nil when it is not set.
+// The stash is encrypted with the configured key.
+func newStash(cfg *config.Config) (*stash, error) {
+	if cfg.Stash == "" {
+		return nil, nil //nolint:nilnil // no stash is a valid state
+	}
+
+	if !cfg.Key.Provided() {
+		return nil, fmt.Errorf("%w: --stash requires a key", config.ErrUsage)
+	}
+
+	encCfg := *cfg
+	encCfg.Structured = false
+	encCfg.Files = nil
+
+	encrypter, err := encryption.NewProcessor(&encCfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating stash processor: %w", err)
+	}
+
+	decCfg := encCfg
+	decCfg.Decrypt = true
+
+	decrypter, err := encryption.NewProcessor(&decCfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating stash processor: %w", err)
+	}
+
+	return &stash{dir: cfg.Stash, encrypter: encrypter, decrypter: decrypter, durable: cfg.Durability()}, nil
+}
+
+// store encrypts the original file into the stash and returns its path relative to the stash directory.
+func (s *stash) store(original string) (string, error) {
+	rel := filepath.Clean(original) + stashExt
+
+	if filepath.IsAbs(rel) || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("%w: cannot stash %q: path leaves the working directory", config.ErrUsage, original)
+	}
+
+	target := filepath.Join(s.dir, rel)
+
+	const ownerOnly = 0o700
+
+	if err := fileutil.MkdirAll(filepath.Dir(target), ownerOnly, s.durable); err != nil {
+		return "", fmt.Errorf("creating stash directory: %w", err)
+	}
+
+	if _, err := s.encrypter.ProcessFile(original, target); err != nil {
+		return "", fmt.Errorf("stashing original: %w", err)
+	}
+
+	return rel, nil
+}
+
+// load decrypts the stash manifest. A missing manifest yields an empty set of entries.
+func (s *stash) load() (map[string]stashEntry, error) {
+	entries := make(map[string]stashEntry)
+
+	file, err := os.Open(filepath.Join(s.dir, stashManifest))
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return entries, nil
+	case err != nil:
+		return nil, fmt.Errorf("opening stash manifest: %w", err)
+	}
+
+	defer file.Close()
+
+	var buf bytes.Buffer
+
+	if _, err := s.decrypter.Decrypt(file, &buf); err != nil {
+		return nil, fmt.Errorf("decrypting stash manifest: %w", err)
+	}
+
+	var list []stashEntry
+
+	if err := json.Unmarshal(buf.Bytes(), &list); err != nil {
+		return nil, fmt.Errorf("decoding stash manifest: %w", err)
+	}
+
+	for _, entry := range list {
+		entries[entry.Original] = entry
+	}
+
+	return entries, nil
+}
+
+// save encrypts the entries into the stash manifest, replacing it atomically.
+// The manifest is removed once no entries remain.
+func (s *stash) save(entries map[string]stashEntry) (err error) {
+	path := filepath.Join(s.dir, stashManifest)
+
+	if len(entries) == 0 {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("removing stash manifest: %w", err)
+		}
+
+		return nil
+	}
+
+	list := make([]stashEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Original < list[j].Original })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding stash manifest: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	defer func() {
+		tmp.Close() //nolint:gosec // best-effort cleanup
+
+		if err != nil {
+			os.Remove(tmp.Name()) //nolint:gosec // best-effort cleanup
+		}
+	}()
+
+	if err = s.encrypter.Encrypt(bytes.NewReader(data), tmp, false); err != nil {
+		return fmt.Errorf("encrypting stash manifest: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("renaming stash manifest: %w", err)
+	}
+
+	return nil
+}
+
+// record merges newly stashed entries into the manifest.
+func (s *stash) record(added []stashEntry) error {
+	if len(added) == 0 {
+		return nil
+	}
+
+	entries, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range added {
+		entries[entry.Original] = entry
+	}
+
+	return s.save(entries)
+}
+
+// discard removes the stashed copies of entries that were not recorded.
+func (s *stash) discard(entries []stashEntry) {
+	for _, entry := range entries {
+		os.Remove(filepath.Join(s.dir, entry.Stash)) //nolint:gosec,errcheck // best-effort cleanup
+	}
+}
+
+// excludes drops files inside the stash directory, so a stash below the processed tree is never redacted.
+func (s *stash) excludes(files []string) []string {
+	kept := files[:0]
+
+	for _, file := range files {
+		if within(file, s.dir) {
+			continue
+		}
+
+		kept = append(kept, file)
+	}
+
+	return kept
+}
+
+// fileSHA256 returns the hex-encoded SHA-256 of a file.
+func fileSHA256(filename string) (string, error) {
+	file, err := os.Open(filename) //nolint:gosec // filename is from resolved file list or the stash manifest
+	if err != nil {
+		return "", fmt.Errorf("opening file: %w", err)
+	}
+
+	defer file.Close()
+
+	hasher := sha256.New()
+
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("reading file: %w", err)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// RunUnredact restores stashed originals of redacted files.
+// All placeholders are verified and all originals decrypted before any file is replaced,
+// so a modified placeholder or a wrong key leaves every file untouched.
+// If replacing a file fails, the files restored before it stay restored and are removed from the manifest.
+//
+//nolint:cyclop,funlen // verify, decrypt and commit phases
+func RunUnredact(cfg *config.Config) error {
+	st, err := newStash(cfg)
+	if err != nil {
+		return err
+	}
+
+	if st == nil {
+		return fmt.Errorf("%w: unredact requires --stash", config.ErrUsage)
+	}
+
+	entries, err := st.load()
+	if err != nil {
+		return err
+	}
+
+	selected := selectStashEntries(entries, cfg.Files)
+	if len(selected) == 0 {
+		return errors.New("no stashed files match the given paths")
+	}
+
+	for _, entry := range selected {
+		sum, err := fileSHA256(entry.Placeholder)
+		if err != nil {
+			return fmt.Errorf("verifying placeholder %q: %w", entry.Placeholder, err)
+		}
+
+		if sum != entry.SHA256 {
+			return fmt.Errorf("verifying placeholder %q: %w", entry.Placeholder, errPlaceholderModified)
+		}
+	}
+
+	if cfg.Dry {
+		for _, entry := range selected {
+			if !cfg.Quiet {
+				fmt.Printf("Restored %q -> %q\n", entry.Placeholder, entry.Original) //nolint:forbidigo
+			}
+		}
+
+		return nil
+	}
+
+	temps := make([]string, 0, len(selected))
+
+	cleanup := func() {
+		for _, tmp := range temps {
+			os.Remove(tmp) //nolint:gosec // best-effort cleanup
+		}
+	}
+
+	for _, entry := range selected {
+		tmp, err := st.extract(entry)
+		if err != nil {
+			cleanup()
+
+			return fmt.Errorf("restoring %q: %w", entry.Original, err)
+		}
+
+		temps = append(temps, tmp)
+	}
+
+	for i, entry := range selected {
+		if err := os.Rename(temps[i], entry.Original); err != nil {
+			cleanup()
+
+			if saveErr := st.save(entries); saveErr != nil {
+				fmt.Fprintf(os.Stderr, "Error updating stash manifest: %v\n", saveErr)
+			}
+
+			return fmt.Errorf("restoring %q: %w", entry.Original, err)
+		}
+
+		temps[i] = ""
+
+		if entry.Placeholder != entry.Original {
+			if err := os.Remove(entry.Placeholder); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				fmt.Fprintf(os.Stderr, "Error deleting %q: %v\n", entry.Placeholder, err)
+			}
+		}
+
+		if err := os.Remove(filepath.Join(st.dir, entry.Stash)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error deleting stashed %q: %v\n", entry.Stash, err)
+		}
+
+		delete(entries, entry.Original)
+
+		if !cfg.Quiet {
+			fmt.Printf("Restored %q -> %q\n", entry.Placeholder, entry.Original) //nolint:forbidigo
+		}
+	}
+
+	if err := st.save(entries); err != nil {
+		return fmt.Errorf("updating stash manifest: %w", err)
+	}
+
+	return nil
+}
+
+// extract decrypts a stashed original into a temporary file next to its destination.
+func (s *stash) extract(entry stashEntry) (tmpName string, err error) {
+	src, err := os.Open(filepath.Join(s.dir, entry.Stash))
+	if err != nil {
+		return "", fmt.Errorf("opening stashed file: %w", err)
+	}
+
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(entry.Original), ".tmp-*")
+	if err != nil {
+		return "", fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	defer func() {
+		tmp.Close() //nolint:gosec // best-effort cleanup
+
+		if err != nil {
+			os.Remove(tmp.Name()) //nolint:gosec // best-effort cleanup
+		}
+	}()
+
+	isExec, err := s.decrypter.Decrypt(src, tmp)
+	if err != nil {
+		return "", fmt.Errorf("decrypting stashed file: %w", err)
+	}
+
+	const ownerReadWrite = 0o600
+
+	perm := os.FileMode(ownerReadWrite)
+
+	if isExec {
+		perm |= 0o111
+	}
+
+	if err = os.Chmod(tmp.Name(), perm); err != nil {
+		return "", fmt.Errorf("setting file permissions: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return "", fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	return tmp.Name(), nil
+}
+
+// selectStashEntries returns the entries whose original or placeholder lies within one of the given paths.
+func selectStashEntries(entries map[string]stashEntry, paths []string) []stashEntry {
+	var selected []stashEntry
+
+	for _, entry := range entries {
+		for _, path := range paths {
+			if within(entry.Original, path) || within(entry.Placeholder, path) {
+				selected = append(selected, entry)
+
+				break
+			}
+		}
+	}
+
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Original < selected[j].Original })
+
+	return selected
+}
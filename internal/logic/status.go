@@ -0,0 +1,221 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/encryption"
+)
+
+// State describes how a plaintext file relates to its encrypted counterpart.
+type State string
+
+const (
+	// StateUpToDate means the ciphertext holds the current plaintext.
+	StateUpToDate State = "up-to-date"
+	// StatePlaintextNewer means the plaintext was edited after it was last encrypted.
+	StatePlaintextNewer State = "plaintext-newer"
+	// StateCiphertextNewer means the ciphertext was updated after the plaintext was last decrypted.
+	StateCiphertextNewer State = "ciphertext-newer"
+	// StateDiffers means the contents differ while both files have the same modification time.
+	StateDiffers State = "differs"
+	// StateMissingCiphertext means the plaintext was never encrypted.
+	StateMissingCiphertext State = "missing-ciphertext"
+	// StateOrphanCiphertext means the ciphertext has no plaintext counterpart.
+	StateOrphanCiphertext State = "orphan-ciphertext"
+)
+
+// Status is the comparison outcome for one plaintext/ciphertext pair.
+type Status struct {
+	// Plaintext file path
+	Plaintext string `json:"plaintext"`
+
+	// Ciphertext file path
+	Ciphertext string `json:"ciphertext"`
+
+	// State of the pair
+	State State `json:"state"`
+
+	// Error that prevented the comparison, if any
+	Error string `json:"error,omitempty"`
+
+	// err is the error the message is derived from
+	err error
+}
+
+// RunStatus compares every resolved file with its counterpart and reports pairs that are out of sync.
+// Content is compared when a key is available; otherwise modification times decide.
+//
+//nolint:cyclop // report loop with text and JSON output
+func RunStatus(cfg *config.Config) error {
+	if cfg.Suffixes.Encrypt == "" {
+		return fmt.Errorf("%w: status requires a non-empty --encrypt-ext", config.ErrUsage)
+	}
+
+	if _, err := resolveFiles(cfg); err != nil {
+		return fmt.Errorf("resolving files: %w", err)
+	}
+
+	var proc *encryption.Processor
+
+	if cfg.Key.Provided() {
+		var err error
+
+		if proc, err = encryption.NewProcessor(cfg); err != nil {
+			return fmt.Errorf("creating processor: %w", err)
+		}
+	}
+
+	statuses := pairFiles(cfg)
+
+	group := errgroup.Group{}
+	group.SetLimit(cfg.Parallel)
+
+	for i := range statuses {
+		group.Go(func() error {
+			compare(&statuses[i], proc)
+
+			return nil
+		})
+	}
+
+	_ = group.Wait() //nolint:errcheck // comparisons record their own errors
+
+	var dirty, errored int
+
+	var first error
+
+	for _, status := range statuses {
+		switch {
+		case status.Error != "":
+			if errored == 0 {
+				first = status.err
+			}
+
+			errored++
+		case status.State != StateUpToDate:
+			dirty++
+		}
+	}
+
+	if cfg.JSON {
+		out, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding status: %w", err)
+		}
+
+		fmt.Println(string(out)) //nolint:forbidigo
+	} else {
+		for _, status := range statuses {
+			switch {
+			case status.Error != "":
+				fmt.Fprintf(os.Stderr, "Error comparing %q: %s\n", status.Plaintext, status.Error)
+			case status.State != StateUpToDate || !cfg.Quiet:
+				fmt.Printf("%-18s %q -> %q\n", status.State, status.Plaintext, status.Ciphertext) //nolint:forbidigo
+			}
+		}
+	}
+
+	switch {
+	case errored > 0:
+		return fmt.Errorf("%d file(s) could not be compared: %w", errored, first)
+	case dirty > 0:
+		return fmt.Errorf("%w: %d file(s) out of sync", ErrCheck, dirty)
+	}
+
+	return nil
+}
+
+// pairFiles groups resolved files into plaintext/ciphertext pairs keyed by ciphertext path.
+func pairFiles(cfg *config.Config) []Status {
+	pairs := make(map[string]*Status)
+
+	for _, file := range cfg.Files {
+		cipher, plain := encryptedPath(file, cfg), file
+
+		if strings.HasSuffix(file, cfg.Suffixes.Encrypt) {
+			cipher, plain = file, decryptedPath(file, cfg)
+		}
+
+		if _, ok := pairs[cipher]; !ok {
+			pairs[cipher] = &Status{Plaintext: plain, Ciphertext: cipher}
+		}
+	}
+
+	statuses := make([]Status, 0, len(pairs))
+
+	for _, status := range pairs {
+		statuses = append(statuses, *status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Plaintext < statuses[j].Plaintext
+	})
+
+	return statuses
+}
+
+// compare fills in the state of a single pair.
+// Without a processor only modification times are compared: the pair is up to date when both are equal,
+// as left by --preserve-timestamps, and otherwise the newer side is reported.
+func compare(status *Status, proc *encryption.Processor) {
+	plainInfo, plainErr := os.Stat(status.Plaintext)
+	cipherInfo, cipherErr := os.Stat(status.Ciphertext)
+
+	switch {
+	case errors.Is(plainErr, fs.ErrNotExist) && cipherErr == nil:
+		status.State = StateOrphanCiphertext
+
+		return
+	case errors.Is(cipherErr, fs.ErrNotExist) && plainErr == nil:
+		status.State = StateMissingCiphertext
+
+		return
+	case plainErr != nil:
+		status.Error, status.err = plainErr.Error(), plainErr
+
+		return
+	case cipherErr != nil:
+		status.Error, status.err = cipherErr.Error(), cipherErr
+
+		return
+	}
+
+	plainNewer := plainInfo.ModTime().After(cipherInfo.ModTime())
+
+	if proc == nil {
+		switch {
+		case plainNewer:
+			status.State = StatePlaintextNewer
+		case cipherInfo.ModTime().After(plainInfo.ModTime()):
+			status.State = StateCiphertextNewer
+		default:
+			status.State = StateUpToDate
+		}
+
+		return
+	}
+
+	matches, err := proc.Matches(status.Plaintext, status.Ciphertext)
+
+	switch {
+	case err != nil:
+		status.Error, status.err = err.Error(), err
+	case matches:
+		status.State = StateUpToDate
+	case plainNewer:
+		status.State = StatePlaintextNewer
+	case cipherInfo.ModTime().After(plainInfo.ModTime()):
+		status.State = StateCiphertextNewer
+	default:
+		status.State = StateDiffers
+	}
+}
@@ -0,0 +1,153 @@
+package logic
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/encryption"
+	"github.com/idelchi/gonc/internal/fileutil"
+	"github.com/idelchi/gonc/internal/structured"
+)
+
+// RunGet prints a single value of a structured file, decrypting it if it is sealed.
+func RunGet(cfg *config.Config, path string) error {
+	file := cfg.Files[0]
+
+	_, leaf, err := lookupLeaf(cfg, file, path)
+	if err != nil {
+		return err
+	}
+
+	value := leaf.Value
+
+	if leaf.Kind == structured.String && encryption.IsSealed(value) {
+		proc, err := encryption.NewProcessor(cfg)
+		if err != nil {
+			return fmt.Errorf("creating processor: %w", err)
+		}
+
+		if _, value, _, err = proc.OpenValue(value, path); err != nil {
+			return fmt.Errorf("decrypting %q: %w", path, err)
+		}
+	}
+
+	fmt.Println(value) //nolint:forbidigo
+
+	return nil
+}
+
+// RunSet replaces a single value of a structured file in place.
+// Sealed values are re-encrypted with the mode they were sealed with; the rest of the file is left byte for byte unchanged.
+// The new value must be valid for the type of the value it replaces.
+func RunSet(cfg *config.Config, path, value string) error {
+	file := cfg.Files[0]
+
+	doc, leaf, err := lookupLeaf(cfg, file, path)
+	if err != nil {
+		return err
+	}
+
+	edit := structured.Edit{Leaf: leaf, Kind: leaf.Kind, Value: value}
+
+	if leaf.Kind == structured.String && encryption.IsSealed(leaf.Value) {
+		proc, err := encryption.NewProcessor(cfg)
+		if err != nil {
+			return fmt.Errorf("creating processor: %w", err)
+		}
+
+		kind, _, _, err := proc.OpenValue(leaf.Value, path)
+		if err != nil {
+			return fmt.Errorf("decrypting %q: %w", path, err)
+		}
+
+		if err := checkKind(kind, value); err != nil {
+			return err
+		}
+
+		edit.Kind = structured.String
+
+		if edit.Value, err = proc.ResealValue(leaf.Value, kind, value, path); err != nil {
+			return fmt.Errorf("encrypting %q: %w", path, err)
+		}
+	} else if err := checkKind(leaf.Kind, value); err != nil {
+		return err
+	}
+
+	out, err := doc.Apply([]structured.Edit{edit})
+	if err != nil {
+		return fmt.Errorf("rewriting document: %w", err)
+	}
+
+	return writeInPlace(file, out)
+}
+
+// lookupLeaf parses a structured file and finds the value at path.
+func lookupLeaf(cfg *config.Config, file, path string) (*structured.Document, structured.Leaf, error) {
+	data, err := os.ReadFile(file) //nolint:gosec // file is a user-supplied positional argument
+	if err != nil {
+		return nil, structured.Leaf{}, fmt.Errorf("reading %q: %w", file, err)
+	}
+
+	doc, err := structured.Parse(strings.TrimSuffix(file, cfg.Suffixes.Encrypt), data)
+	if err != nil {
+		return nil, structured.Leaf{}, fmt.Errorf("parsing %q: %w", file, err)
+	}
+
+	leaf, ok := doc.Lookup(path)
+	if !ok {
+		return nil, structured.Leaf{}, fmt.Errorf("%w: no value at %q in %q", config.ErrUsage, path, file)
+	}
+
+	return doc, leaf, nil
+}
+
+// checkKind validates that value can replace a value of the given kind.
+func checkKind(kind structured.Kind, value string) error {
+	switch kind {
+	case structured.Number:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("%w: %q is not a number", config.ErrUsage, value)
+		}
+	case structured.Bool:
+		if value != "true" && value != "false" {
+			return fmt.Errorf("%w: %q is not a boolean", config.ErrUsage, value)
+		}
+	case structured.Null:
+		return errors.New("cannot set a null value")
+	case structured.String, structured.Raw:
+	}
+
+	return nil
+}
+
+// writeInPlace atomically replaces file with data, keeping its permissions.
+func writeInPlace(file string, data []byte) (err error) {
+	tc, err := fileutil.NewTempContext(file, file, false)
+	if err != nil {
+		return fmt.Errorf("preparing atomic write: %w", err)
+	}
+
+	defer tc.CleanupOnError(&err)
+
+	if _, err = tc.TmpFile.Write(data); err != nil {
+		return fmt.Errorf("writing content: %w", err)
+	}
+
+	if err = os.Chmod(tc.TmpName, tc.SrcInfo.Mode().Perm()); err != nil {
+		return fmt.Errorf("setting file permissions: %w", err)
+	}
+
+	if err = tc.TmpFile.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	if err = os.Rename(tc.TmpName, file); err != nil {
+		return fmt.Errorf("renaming output file: %w", err)
+	}
+
+	return nil
+}
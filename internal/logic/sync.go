@@ -0,0 +1,539 @@
+package logic
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/encryption"
+	"github.com/idelchi/gonc/internal/filter"
+)
+
+// syncStateFile records, inside the plaintext directory, what both sides looked like after the last sync.
+// It is kept next to the plaintext so that every clone has its own sync base.
+const syncStateFile = ".gonc-sync.json"
+
+// Sync policies for --prefer.
+const (
+	// preferPlain resolves conflicts in favor of the plaintext tree.
+	preferPlain = "plain"
+	// preferEncrypted resolves conflicts in favor of the encrypted tree.
+	preferEncrypted = "encrypted"
+)
+
+// Sync actions planned for a file pair.
+const (
+	actionNone         = ""
+	actionRecord       = "record"
+	actionEncrypt      = "encrypt"
+	actionDecrypt      = "decrypt"
+	actionDeletePlain  = "delete-plain"
+	actionDeleteCipher = "delete-encrypted"
+	actionForget       = "forget"
+	actionConflict     = "conflict"
+)
+
+// syncState is the last synced state of one file pair.
+type syncState struct {
+	// Plain is the keyed digest of the plaintext
+	Plain string `json:"plain"`
+
+	// Encrypted is the SHA-256 of the ciphertext file
+	Encrypted string `json:"encrypted"`
+}
+
+// syncPair is one relative path present in either tree or the sync state.
+type syncPair struct {
+	rel    string
+	plain  string
+	cipher string
+	state  *syncState
+	action string
+	reason string
+	err    error
+}
+
+// syncer holds the processors and directories of a sync run.
+type syncer struct {
+	cfg       *config.Config
+	plainDir  string
+	encDir    string
+	encrypter *encryption.Processor
+	decrypter *encryption.Processor
+}
+
+// RunSync brings a plaintext tree and an encrypted tree in line with each other.
+// Changes since the last sync are detected from plaintext digests and ciphertext hashes;
+// files changed on both sides are conflicts, resolved only with --prefer.
+//
+//nolint:cyclop,funlen,gocognit // plan, report and apply phases
+func RunSync(cfg *config.Config, plainDir, encDir string) error {
+	if cfg.Suffixes.Encrypt == "" {
+		return fmt.Errorf("%w: sync requires a non-empty --encrypt-ext", config.ErrUsage)
+	}
+
+	if cfg.Key.Provider != "" {
+		return fmt.Errorf("%w: sync keys its plaintext digests with the key, --key-provider is not supported", config.ErrUsage)
+	}
+
+	plainDir, encDir = filepath.Clean(plainDir), filepath.Clean(encDir)
+
+	if within(plainDir, encDir) || within(encDir, plainDir) {
+		return fmt.Errorf("%w: %q and %q must not overlap", config.ErrUsage, plainDir, encDir)
+	}
+
+	syn, err := newSyncer(cfg, plainDir, encDir)
+	if err != nil {
+		return err
+	}
+
+	states, err := syn.loadState()
+	if err != nil {
+		return err
+	}
+
+	pairs, err := syn.pairs(states)
+	if err != nil {
+		return err
+	}
+
+	group := errgroup.Group{}
+	group.SetLimit(cfg.Parallel)
+
+	for _, pair := range pairs {
+		group.Go(func() error {
+			syn.plan(pair)
+
+			return nil
+		})
+	}
+
+	_ = group.Wait() //nolint:errcheck // pairs record their own errors
+
+	var conflicts, errored int
+
+	for _, pair := range pairs {
+		switch {
+		case pair.err != nil:
+			errored++
+
+			fmt.Fprintf(os.Stderr, "Error comparing %q: %v\n", pair.rel, pair.err)
+		case pair.action == actionConflict:
+			conflicts++
+
+			fmt.Fprintf(os.Stderr, "Conflict %q: %s\n", pair.rel, pair.reason)
+		case cfg.Dry && pair.action != actionNone && pair.action != actionRecord && !cfg.Quiet:
+			fmt.Printf("%-16s %q\n", pair.action, pair.rel) //nolint:forbidigo
+		}
+	}
+
+	if cfg.Dry {
+		return syncError(conflicts, errored)
+	}
+
+	var mu sync.Mutex
+
+	for _, pair := range pairs {
+		if pair.err != nil || pair.action == actionConflict {
+			continue
+		}
+
+		group.Go(func() error {
+			state, err := syn.apply(pair)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			switch {
+			case err != nil:
+				errored++
+
+				fmt.Fprintf(os.Stderr, "Error syncing %q: %v\n", pair.rel, err)
+			case state == nil:
+				delete(states, pair.rel)
+			default:
+				states[pair.rel] = *state
+			}
+
+			if err == nil && pair.action != actionNone && pair.action != actionRecord &&
+				pair.action != actionForget && !cfg.Quiet {
+				fmt.Printf("%-16s %q\n", pair.action, pair.rel) //nolint:forbidigo
+			}
+
+			return nil
+		})
+	}
+
+	_ = group.Wait() //nolint:errcheck // pairs report their own errors
+
+	if err := syn.saveState(states); err != nil {
+		return err
+	}
+
+	return syncError(conflicts, errored)
+}
+
+// syncError summarizes unresolved conflicts and errors of a sync run.
+func syncError(conflicts, errored int) error {
+	switch {
+	case errored > 0:
+		return fmt.Errorf("%w: %d file(s) could not be synced", ErrPartial, errored)
+	case conflicts > 0:
+		return fmt.Errorf("%w: %d conflict(s), resolve them or rerun with --prefer", ErrCheck, conflicts)
+	}
+
+	return nil
+}
+
+// newSyncer creates the encrypting and decrypting processors for a sync run.
+func newSyncer(cfg *config.Config, plainDir, encDir string) (*syncer, error) {
+	encCfg := *cfg
+	encCfg.Files = nil
+	// Sync decides itself which side may be replaced.
+	encCfg.Force, encCfg.Backup = true, false
+
+	encrypter, err := encryption.NewProcessor(&encCfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating processor: %w", err)
+	}
+
+	decCfg := encCfg
+	decCfg.Decrypt = true
+
+	decrypter, err := encryption.NewProcessor(&decCfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating processor: %w", err)
+	}
+
+	return &syncer{cfg: cfg, plainDir: plainDir, encDir: encDir, encrypter: encrypter, decrypter: decrypter}, nil
+}
+
+// pairs resolves both trees and joins them with the sync state by relative path.
+// Include/exclude patterns are matched against the relative plaintext path.
+func (s *syncer) pairs(states map[string]syncState) ([]*syncPair, error) {
+	flt, hasIncludes, err := entryFilter(s.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	byRel := make(map[string]*syncPair)
+
+	get := func(rel string) *syncPair {
+		if pair, ok := byRel[rel]; ok {
+			return pair
+		}
+
+		pair := &syncPair{
+			rel:    rel,
+			plain:  filepath.Join(s.plainDir, filepath.FromSlash(rel)),
+			cipher: filepath.Join(s.encDir, filepath.FromSlash(rel)+s.cfg.Suffixes.Encrypt),
+		}
+
+		byRel[rel] = pair
+
+		return pair
+	}
+
+	plainFiles, err := resolveTree(s.plainDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range plainFiles {
+		rel, _ := filepath.Rel(s.plainDir, file) //nolint:errcheck // file is below plainDir
+		rel = filepath.ToSlash(rel)
+
+		if rel == syncStateFile || !flt.Match(rel, hasIncludes) {
+			continue
+		}
+
+		get(rel)
+	}
+
+	cipherFiles, err := resolveTree(s.encDir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range cipherFiles {
+		rel, _ := filepath.Rel(s.encDir, file) //nolint:errcheck // file is below encDir
+		rel = filepath.ToSlash(rel)
+
+		if !strings.HasSuffix(rel, s.cfg.Suffixes.Encrypt) {
+			continue
+		}
+
+		rel = strings.TrimSuffix(rel, s.cfg.Suffixes.Encrypt)
+
+		if !flt.Match(rel, hasIncludes) {
+			continue
+		}
+
+		get(rel)
+	}
+
+	for rel, state := range states {
+		if flt.Match(rel, hasIncludes) {
+			get(rel).state = &state
+		}
+	}
+
+	pairs := make([]*syncPair, 0, len(byRel))
+
+	for _, pair := range byRel {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].rel < pairs[j].rel })
+
+	return pairs, nil
+}
+
+// resolveTree lists the files below dir with filter.Resolve. A missing or empty directory has no files.
+func resolveTree(dir string) ([]string, error) {
+	if _, err := os.Stat(dir); errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	files, _, err := filter.Resolve([]string{dir}, nil, nil, false)
+	if errors.Is(err, filter.ErrNoFiles) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("resolving %q: %w", dir, err)
+	}
+
+	return files, nil
+}
+
+// plan decides the action for a pair.
+//
+//nolint:cyclop,gocognit,funlen // decision table
+func (s *syncer) plan(pair *syncPair) {
+	plainDigest, plainExists, err := s.plainDigest(pair.plain)
+	if err != nil {
+		pair.err = err
+
+		return
+	}
+
+	cipherHash, cipherExists, err := cipherHash(pair.cipher)
+	if err != nil {
+		pair.err = err
+
+		return
+	}
+
+	state := pair.state
+	plainChanged := state == nil || plainDigest != state.Plain
+	cipherChanged := state == nil || cipherHash != state.Encrypted
+
+	switch {
+	case !plainExists && !cipherExists:
+		pair.action = actionForget
+	case plainExists && !cipherExists && (state == nil || plainChanged):
+		pair.action = actionEncrypt
+
+		if state != nil {
+			pair.action, pair.reason = actionConflict, "plaintext changed, ciphertext was deleted"
+		}
+	case plainExists && !cipherExists:
+		pair.action = actionDeletePlain
+	case !plainExists && (state == nil || cipherChanged):
+		pair.action = actionDecrypt
+
+		if state != nil {
+			pair.action, pair.reason = actionConflict, "ciphertext changed, plaintext was deleted"
+		}
+	case !plainExists:
+		pair.action = actionDeleteCipher
+	case state != nil && !plainChanged && !cipherChanged:
+		pair.action = actionNone
+	case state != nil && plainChanged && !cipherChanged:
+		pair.action = actionEncrypt
+	case state != nil && !plainChanged && cipherChanged:
+		pair.action = actionDecrypt
+	default:
+		matches, err := s.decrypter.Matches(pair.plain, pair.cipher)
+
+		switch {
+		case err != nil:
+			pair.err = err
+		case matches:
+			pair.action = actionRecord
+		default:
+			pair.action, pair.reason = actionConflict, "both sides changed"
+		}
+	}
+
+	if pair.action == actionConflict {
+		s.resolve(pair, plainExists, cipherExists)
+	}
+}
+
+// resolve applies the --prefer policy to a conflicting pair.
+func (s *syncer) resolve(pair *syncPair, plainExists, cipherExists bool) {
+	switch s.cfg.Prefer {
+	case preferPlain:
+		pair.action = actionEncrypt
+
+		if !plainExists {
+			pair.action = actionDeleteCipher
+		}
+	case preferEncrypted:
+		pair.action = actionDecrypt
+
+		if !cipherExists {
+			pair.action = actionDeletePlain
+		}
+	}
+}
+
+// apply performs the planned action and returns the new sync state of the pair, or nil when it is gone.
+func (s *syncer) apply(pair *syncPair) (*syncState, error) {
+	switch pair.action {
+	case actionNone:
+		return pair.state, nil
+	case actionEncrypt:
+		if _, err := s.encrypter.ProcessFile(pair.plain, pair.cipher); err != nil {
+			return pair.state, err
+		}
+	case actionDecrypt:
+		if _, err := s.decrypter.ProcessFile(pair.cipher, pair.plain); err != nil {
+			return pair.state, err
+		}
+	case actionDeletePlain:
+		if err := os.Remove(pair.plain); err != nil {
+			return pair.state, fmt.Errorf("deleting %q: %w", pair.plain, err)
+		}
+
+		return nil, nil
+	case actionDeleteCipher:
+		if err := os.Remove(pair.cipher); err != nil {
+			return pair.state, fmt.Errorf("deleting %q: %w", pair.cipher, err)
+		}
+
+		return nil, nil
+	case actionForget:
+		return nil, nil
+	}
+
+	plainDigest, _, err := s.plainDigest(pair.plain)
+	if err != nil {
+		return pair.state, err
+	}
+
+	cipherHash, _, err := cipherHash(pair.cipher)
+	if err != nil {
+		return pair.state, err
+	}
+
+	return &syncState{Plain: plainDigest, Encrypted: cipherHash}, nil
+}
+
+// plainDigest returns the keyed digest of a plaintext file, and whether it exists.
+func (s *syncer) plainDigest(path string) (string, bool, error) {
+	file, err := os.Open(path) //nolint:gosec // path is below the plaintext directory
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return "", false, nil
+	case err != nil:
+		return "", false, fmt.Errorf("opening %q: %w", path, err)
+	}
+
+	defer file.Close()
+
+	digest, err := s.encrypter.Digest(file)
+	if err != nil {
+		return "", false, fmt.Errorf("hashing %q: %w", path, err)
+	}
+
+	return hex.EncodeToString(digest), true, nil
+}
+
+// cipherHash returns the SHA-256 of a ciphertext file, and whether it exists.
+func cipherHash(path string) (string, bool, error) {
+	sum, err := fileSHA256(path)
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return "", false, nil
+	case err != nil:
+		return "", false, fmt.Errorf("hashing %q: %w", path, err)
+	}
+
+	return sum, true, nil
+}
+
+// loadState reads the sync state of the plaintext directory. A missing state is empty.
+func (s *syncer) loadState() (map[string]syncState, error) {
+	states := make(map[string]syncState)
+
+	data, err := os.ReadFile(filepath.Join(s.plainDir, syncStateFile))
+
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return states, nil
+	case err != nil:
+		return nil, fmt.Errorf("reading sync state: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("parsing sync state: %w", err)
+	}
+
+	return states, nil
+}
+
+// saveState writes the sync state into the plaintext directory atomically.
+func (s *syncer) saveState(states map[string]syncState) (err error) {
+	const ownerOnly = 0o700
+
+	if err := os.MkdirAll(s.plainDir, ownerOnly); err != nil {
+		return fmt.Errorf("creating %q: %w", s.plainDir, err)
+	}
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding sync state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(s.plainDir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	defer func() {
+		tmp.Close() //nolint:gosec // best-effort cleanup
+
+		if err != nil {
+			os.Remove(tmp.Name()) //nolint:gosec // best-effort cleanup
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return fmt.Errorf("writing sync state: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	if err = os.Rename(tmp.Name(), filepath.Join(s.plainDir, syncStateFile)); err != nil {
+		return fmt.Errorf("renaming sync state: %w", err)
+	}
+
+	return nil
+}
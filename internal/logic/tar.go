@@ -0,0 +1,188 @@
+package logic
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/idelchi/gonc/internal/config"
+	"github.com/idelchi/gonc/internal/encryption"
+	"github.com/idelchi/gonc/internal/report"
+)
+
+// RunTar filters a tar stream from reader to writer, encrypting or decrypting the data of selected regular entries.
+// Encrypted entries get the encrypt suffix appended to their names; decrypted entries have it stripped.
+// Hard links to a transformed entry are renamed alongside it. Directories, symlinks and unselected entries
+// pass through unchanged. Encrypted entries are streamed, as the size of their output follows from the size of their
+// input. Decrypted entries are spooled to a temporary file and only written once they are authenticated.
+//
+//nolint:cyclop,funlen // single pass over the stream
+func RunTar(cfg *config.Config, reader io.Reader, writer io.Writer) error {
+	start := time.Now()
+
+	var defaults []string
+
+	if cfg.Decrypt {
+		defaults = append(defaults, "*"+cfg.Suffixes.Encrypt)
+	}
+
+	flt, hasIncludes, err := entryFilter(cfg, defaults...)
+	if err != nil {
+		return err
+	}
+
+	proc, err := encryption.NewProcessor(cfg)
+	if err != nil {
+		return fmt.Errorf("creating processor: %w", err)
+	}
+
+	input := tar.NewReader(reader)
+	output := tar.NewWriter(writer)
+
+	rename := func(name string) string {
+		if cfg.Decrypt {
+			return strings.TrimSuffix(name, cfg.Suffixes.Encrypt) + cfg.Suffixes.Decrypt
+		}
+
+		return name + cfg.Suffixes.Encrypt
+	}
+
+	var (
+		scanned, processed int
+		totalSize          int64
+	)
+
+	// renamed maps the names of transformed entries to their new names, for hard links to follow them.
+	renamed := make(map[string]string)
+
+	for {
+		header, err := input.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("reading tar stream: %w", err)
+		}
+
+		if header.Typeflag == tar.TypeLink {
+			if target, ok := renamed[header.Linkname]; ok {
+				header.Name, header.Linkname = rename(header.Name), target
+			}
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			if err := output.WriteHeader(header); err != nil {
+				return fmt.Errorf("writing header for %q: %w", header.Name, err)
+			}
+
+			continue
+		}
+
+		scanned++
+
+		if !flt.Match(strings.TrimPrefix(header.Name, "./"), hasIncludes) {
+			if err := copyTarEntry(output, input, header); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		name, size := header.Name, header.Size
+		header.Name = rename(name)
+
+		open := func(size int64) (io.Writer, error) {
+			header.Size = size
+
+			if err := output.WriteHeader(header); err != nil {
+				return nil, fmt.Errorf("writing header for %q: %w", header.Name, err)
+			}
+
+			return output, nil
+		}
+
+		if cfg.Decrypt {
+			if err := decryptTarEntry(proc, input, size, header, output); err != nil {
+				return fmt.Errorf("decrypting %q: %w", name, err)
+			}
+		} else {
+			const executableBits = 0o111
+
+			if err := proc.EncryptSized(input, size, header.Mode&executableBits != 0, open); err != nil {
+				return fmt.Errorf("encrypting %q: %w", name, err)
+			}
+		}
+
+		renamed[name] = header.Name
+
+		processed++
+
+		totalSize += header.Size
+	}
+
+	if err := output.Close(); err != nil {
+		return fmt.Errorf("closing tar stream: %w", err)
+	}
+
+	if cfg.Stats {
+		report.WriteStats(os.Stderr, report.Summary{
+			Scanned:   scanned,
+			Excluded:  scanned - processed,
+			Processed: processed,
+			Size:      totalSize,
+			Duration:  report.Milliseconds(time.Since(start)),
+		})
+	}
+
+	return nil
+}
+
+// decryptTarEntry decrypts an entry into a temporary file and writes it to output only once it is authenticated,
+// so that the data of a tampered entry never reaches the output stream.
+func decryptTarEntry(
+	proc *encryption.Processor,
+	input io.Reader,
+	size int64,
+	header *tar.Header,
+	output *tar.Writer,
+) error {
+	spool, err := os.CreateTemp("", ".gonc-tar-*")
+	if err != nil {
+		return fmt.Errorf("creating spool file: %w", err)
+	}
+
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	if err := proc.DecryptSized(input, size, func(size int64) (io.Writer, error) {
+		header.Size = size
+
+		return spool, nil
+	}); err != nil {
+		return err //nolint:wrapcheck // wrapped by the caller
+	}
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewinding spool file: %w", err)
+	}
+
+	return copyTarEntry(output, spool, header)
+}
+
+// copyTarEntry writes an entry to output unchanged.
+func copyTarEntry(output *tar.Writer, input io.Reader, header *tar.Header) error {
+	if err := output.WriteHeader(header); err != nil {
+		return fmt.Errorf("writing header for %q: %w", header.Name, err)
+	}
+
+	if _, err := io.Copy(output, input); err != nil { //nolint:gosec // size is bounded by the entry header
+		return fmt.Errorf("copying %q: %w", header.Name, err)
+	}
+
+	return nil
+}
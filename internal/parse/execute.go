@@ -5,6 +5,8 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/spf13/cobra"
+
 	"github.com/idelchi/gogen/pkg/cobraext"
 	"github.com/idelchi/gonc/internal/commands"
 	"github.com/idelchi/gonc/internal/config"
@@ -16,6 +18,8 @@ func Execute(version string) error {
 	cfg := &config.Config{}
 	root := commands.NewRootCommand(cfg, version)
 
+	usageErrors(root)
+
 	switch err := root.Execute(); {
 	case errors.Is(err, cobraext.ErrExitGracefully):
 		return nil
@@ -25,3 +29,24 @@ func Execute(version string) error {
 		return nil
 	}
 }
+
+// usageErrors marks invalid flags and arguments of cmd and its subcommands as config.ErrUsage.
+func usageErrors(cmd *cobra.Command) {
+	cmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return fmt.Errorf("%w: %w", config.ErrUsage, err)
+	})
+
+	if args := cmd.Args; args != nil {
+		cmd.Args = func(cmd *cobra.Command, positional []string) error {
+			if err := args(cmd, positional); err != nil {
+				return fmt.Errorf("%w: %w", config.ErrUsage, err)
+			}
+
+			return nil
+		}
+	}
+
+	for _, sub := range cmd.Commands() {
+		usageErrors(sub)
+	}
+}
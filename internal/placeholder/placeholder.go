@@ -0,0 +1,175 @@
+// Package placeholder selects the redaction content for a file from path-pattern rules.
+// Rule contents are text/template templates rendered with details of the original file.
+package placeholder
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/tidwall/jsonc"
+
+	"github.com/idelchi/gonc/pkg/pathmatch"
+)
+
+// Rule maps a path pattern to a content template.
+type Rule struct {
+	// Pattern is matched against the file path with find -path semantics
+	Pattern string `json:"pattern"`
+
+	// Content is the template for the redacted content
+	Content string `json:"content"`
+}
+
+// Vars are the variables available to content templates.
+type Vars struct {
+	// Path of the original file
+	Path string
+
+	// Size of the original file in bytes
+	Size int64
+
+	// SHA256 is the hex-encoded SHA-256 of the original file
+	SHA256 string
+
+	// Lines is the number of lines in the original file
+	Lines int
+}
+
+// compiled is a rule with its pattern and template prepared.
+type compiled struct {
+	matcher *pathmatch.Matcher
+	tmpl    *template.Template
+}
+
+// Templates chooses the content for a file: the first matching rule wins, otherwise the fallback is used.
+type Templates struct {
+	rules    []compiled
+	fallback string
+}
+
+// New compiles the rules. Files matching no rule get the fallback content verbatim.
+func New(rules []Rule, fallback string) (*Templates, error) {
+	templates := &Templates{fallback: fallback, rules: make([]compiled, 0, len(rules))}
+
+	for _, rule := range rules {
+		matcher, err := pathmatch.NewMatcher([]string{strings.TrimPrefix(rule.Pattern, "./")})
+		if err != nil {
+			return nil, fmt.Errorf("compiling content rules: %w", err)
+		}
+
+		tmpl, err := template.New(rule.Pattern).Option("missingkey=error").Parse(rule.Content)
+		if err != nil {
+			return nil, fmt.Errorf("template for %q: %w", rule.Pattern, err)
+		}
+
+		if err := tmpl.Execute(io.Discard, Vars{}); err != nil {
+			return nil, fmt.Errorf("template for %q: %w", rule.Pattern, err)
+		}
+
+		templates.rules = append(templates.rules, compiled{matcher: matcher, tmpl: tmpl})
+	}
+
+	return templates, nil
+}
+
+// Load reads rules from a JSONC file holding a list of {"pattern", "content"} objects.
+func Load(path, fallback string) (*Templates, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is from user-supplied config
+	if err != nil {
+		return nil, fmt.Errorf("reading content rules file %q: %w", path, err)
+	}
+
+	var rules []Rule
+	if err := json.Unmarshal(jsonc.ToJSONInPlace(data), &rules); err != nil {
+		return nil, fmt.Errorf("parsing content rules file %q: %w", path, err)
+	}
+
+	return New(rules, fallback)
+}
+
+// Content returns the redaction content for the file at path.
+func (t *Templates) Content(path string) (string, error) {
+	slashed := filepath.ToSlash(filepath.Clean(path))
+
+	for _, rule := range t.rules {
+		if !rule.matcher.MatchAny(slashed) {
+			continue
+		}
+
+		vars, err := describe(path)
+		if err != nil {
+			return "", err
+		}
+
+		var buf bytes.Buffer
+
+		if err := rule.tmpl.Execute(&buf, vars); err != nil {
+			return "", fmt.Errorf("rendering content for %q: %w", path, err)
+		}
+
+		return buf.String(), nil
+	}
+
+	return t.fallback, nil
+}
+
+// describe collects the template variables of a file in a single pass.
+func describe(path string) (Vars, error) {
+	file, err := os.Open(path) //nolint:gosec // path is from resolved file list, not user input
+	if err != nil {
+		return Vars{}, fmt.Errorf("opening file: %w", err)
+	}
+
+	defer file.Close()
+
+	hasher := sha256.New()
+	counter := &lineCounter{}
+
+	if _, err := io.Copy(io.MultiWriter(hasher, counter), file); err != nil {
+		return Vars{}, fmt.Errorf("reading file: %w", err)
+	}
+
+	return Vars{
+		Path:   filepath.ToSlash(path),
+		Size:   counter.size,
+		SHA256: hex.EncodeToString(hasher.Sum(nil)),
+		Lines:  counter.count(),
+	}, nil
+}
+
+// lineCounter counts bytes and lines written to it.
+type lineCounter struct {
+	size  int64
+	lines int
+	last  byte
+}
+
+// Write implements io.Writer.
+func (c *lineCounter) Write(data []byte) (int, error) {
+	if len(data) == 0 {
+		return 0, nil
+	}
+
+	c.size += int64(len(data))
+	c.lines += bytes.Count(data, []byte{'\n'})
+	c.last = data[len(data)-1]
+
+	return len(data), nil
+}
+
+// count returns the number of lines, including a final line without a newline.
+func (c *lineCounter) count() int {
+	if c.size > 0 && c.last != '\n' {
+		return c.lines + 1
+	}
+
+	return c.lines
+}
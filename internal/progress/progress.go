@@ -0,0 +1,260 @@
+// Package progress shows the live progress of a run on standard error.
+// On a terminal, a single status line is redrawn in place; otherwise a log line is printed periodically.
+// All methods are safe on a nil *Tracker, which disables progress reporting.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+const (
+	// terminalInterval is the redraw interval of the status line.
+	terminalInterval = 200 * time.Millisecond
+	// logInterval is the interval of log lines when standard error is not a terminal.
+	logInterval = 5 * time.Second
+	// smoothing weighs the latest sample of the current throughput.
+	smoothing = 0.3
+)
+
+// Tracker counts processed bytes and files across all workers.
+type Tracker struct {
+	// total bytes expected to be read
+	total int64
+
+	// bytes read so far
+	bytes atomic.Int64
+
+	// files finished so far
+	files atomic.Int64
+
+	// totalFiles is the number of files of the run
+	totalFiles int64
+
+	// start of the run
+	start time.Time
+
+	// out receives the progress output
+	out *os.File
+
+	// terminal reports whether out is a terminal
+	terminal bool
+
+	// mu guards drawn, rate and the last sample
+	mu sync.Mutex
+
+	// drawn reports whether a status line is on screen
+	drawn bool
+
+	// rate is the smoothed current throughput in bytes per second
+	rate float64
+
+	// lastBytes and lastTime are the previous sample
+	lastBytes int64
+	lastTime  time.Time
+
+	// stop ends the render loop, which closes stopped
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// New creates a tracker for a run over the given number of files and bytes.
+func New(files int, bytes int64) *Tracker {
+	tracker := &Tracker{
+		total:      bytes,
+		totalFiles: int64(files),
+		out:        os.Stderr,
+		stop:       make(chan struct{}),
+		stopped:    make(chan struct{}),
+	}
+
+	if info, err := tracker.out.Stat(); err == nil {
+		tracker.terminal = info.Mode()&os.ModeCharDevice != 0
+	}
+
+	return tracker
+}
+
+// Start begins rendering progress until Stop is called.
+func (t *Tracker) Start() {
+	if t == nil {
+		return
+	}
+
+	t.start = time.Now()
+	t.lastTime = t.start
+
+	interval := logInterval
+
+	if t.terminal {
+		interval = terminalInterval
+	}
+
+	go func() {
+		defer close(t.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-t.stop:
+				return
+			case <-ticker.C:
+				t.render()
+			}
+		}
+	}()
+}
+
+// Stop ends rendering, leaving the final state on screen.
+func (t *Tracker) Stop() {
+	if t == nil {
+		return
+	}
+
+	close(t.stop)
+	<-t.stopped
+
+	t.render()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.drawn {
+		fmt.Fprintln(t.out)
+
+		t.drawn = false
+	}
+}
+
+// Add counts bytes that were processed without a counting reader.
+func (t *Tracker) Add(bytes int64) {
+	if t == nil {
+		return
+	}
+
+	t.bytes.Add(bytes)
+}
+
+// FileDone counts a finished file, whether it succeeded or not.
+func (t *Tracker) FileDone() {
+	if t == nil {
+		return
+	}
+
+	t.files.Add(1)
+}
+
+// Reader returns a reader that counts the bytes read through it.
+func (t *Tracker) Reader(reader io.Reader) io.Reader {
+	if t == nil {
+		return reader
+	}
+
+	return &countingReader{tracker: t, reader: reader}
+}
+
+// Writer returns a writer that clears the status line before each write, so that
+// other output is not mixed into it. The next redraw puts the status line back.
+func (t *Tracker) Writer(writer io.Writer) io.Writer {
+	if t == nil || !t.terminal {
+		return writer
+	}
+
+	return &clearingWriter{tracker: t, writer: writer}
+}
+
+// render prints the current state.
+func (t *Tracker) render() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	bytes, total := t.bytes.Load(), max(t.total, t.bytes.Load())
+
+	if elapsed := now.Sub(t.lastTime).Seconds(); elapsed > 0 {
+		sample := float64(bytes-t.lastBytes) / elapsed
+
+		if t.lastBytes == 0 && t.rate == 0 {
+			t.rate = sample
+		} else {
+			t.rate = smoothing*sample + (1-smoothing)*t.rate
+		}
+	}
+
+	t.lastBytes, t.lastTime = bytes, now
+
+	percent := 100.0
+
+	if total > 0 {
+		percent = float64(bytes) / float64(total) * 100 //nolint:mnd // percentage
+	}
+
+	eta := "-"
+
+	if average := float64(bytes) / now.Sub(t.start).Seconds(); average > 0 && bytes < total {
+		eta = (time.Duration(float64(total-bytes)/average) * time.Second).Round(time.Second).String()
+	}
+
+	//nolint:gosec // byte counts are never negative
+	line := fmt.Sprintf("%s / %s (%.0f%%) | %d/%d files | %s/s | ETA %s",
+		humanize.IBytes(uint64(bytes)), humanize.IBytes(uint64(total)), percent,
+		t.files.Load(), t.totalFiles, humanize.IBytes(uint64(max(0, t.rate))), eta)
+
+	if t.terminal {
+		fmt.Fprintf(t.out, "\r\033[K%s", line)
+
+		t.drawn = true
+
+		return
+	}
+
+	fmt.Fprintf(t.out, "Progress: %s\n", line)
+}
+
+// clear removes the status line. The caller holds the lock.
+func (t *Tracker) clear() {
+	if t.drawn {
+		fmt.Fprint(t.out, "\r\033[K")
+
+		t.drawn = false
+	}
+}
+
+// countingReader adds the bytes read through it to its tracker.
+type countingReader struct {
+	tracker *Tracker
+	reader  io.Reader
+}
+
+// Read implements io.Reader.
+func (r *countingReader) Read(data []byte) (int, error) {
+	n, err := r.reader.Read(data)
+
+	r.tracker.bytes.Add(int64(n))
+
+	return n, err //nolint:wrapcheck // transparent wrapper
+}
+
+// clearingWriter clears the status line of its tracker before writing.
+type clearingWriter struct {
+	tracker *Tracker
+	writer  io.Writer
+}
+
+// Write implements io.Writer.
+func (w *clearingWriter) Write(data []byte) (int, error) {
+	w.tracker.mu.Lock()
+	defer w.tracker.mu.Unlock()
+
+	w.tracker.clear()
+
+	return w.writer.Write(data) //nolint:wrapcheck // transparent wrapper
+}
@@ -0,0 +1,314 @@
+// Package report prints the outcome of a run, either as human-readable text or as JSON events.
+// In the JSON formats, every processed file yields one event and the run ends with a summary.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Output formats.
+const (
+	// FormatText prints human-readable lines.
+	FormatText = "text"
+	// FormatJSON prints a single JSON document holding all events and the summary.
+	FormatJSON = "json"
+	// FormatNDJSON prints one JSON object per line, ending with the summary.
+	FormatNDJSON = "ndjson"
+)
+
+// Error classes that text output does not print, as the run reports them as a whole.
+const (
+	// ClassCanceled is the class of files aborted by an interrupt.
+	ClassCanceled = "canceled"
+	// ClassRolledBack is the class of outputs discarded with a failed batch.
+	ClassRolledBack = "rolled_back"
+)
+
+// NoMatches is the error reported for a pattern that matched no files.
+const NoMatches = "matched no files"
+
+// File is the outcome of a single processed file.
+type File struct {
+	// Type is always "file"
+	Type string `json:"type"`
+
+	// Command that processed the file
+	Command string `json:"command"`
+
+	// Input file path
+	Input string `json:"input"`
+
+	// Output file path
+	Output string `json:"output,omitempty"`
+
+	// InputSize in bytes
+	InputSize int64 `json:"input_size"`
+
+	// OutputSize in bytes
+	OutputSize int64 `json:"output_size"`
+
+	// Mode the file was processed with
+	Mode string `json:"mode,omitempty"`
+
+	// Secrets replaced by --in-place-secrets
+	Secrets *int `json:"secrets,omitempty"`
+
+	// Duration of the processing in milliseconds
+	Duration float64 `json:"duration_ms"`
+
+	// Unchanged reports that the existing output already held the same content
+	Unchanged bool `json:"unchanged,omitempty"`
+
+	// Deleted reports whether the input was removed
+	Deleted bool `json:"deleted"`
+
+	// DryRun marks files that would be processed
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Error message, if processing failed
+	Error string `json:"error,omitempty"`
+
+	// ErrorClass groups errors by cause
+	ErrorClass string `json:"error_class,omitempty"`
+
+	// Err is the error the message and class are derived from
+	Err error `json:"-"`
+}
+
+// Pattern is the outcome of checking a single include or exclude pattern.
+type Pattern struct {
+	// Type is always "pattern"
+	Type string `json:"type"`
+
+	// Kind is "include" or "exclude"
+	Kind string `json:"kind"`
+
+	// Pattern as given
+	Pattern string `json:"pattern"`
+
+	// Matches is the number of files the pattern matched
+	Matches int `json:"matches"`
+
+	// Error message, if the pattern is invalid or matched nothing
+	Error string `json:"error,omitempty"`
+}
+
+// Summary is the final event of a run.
+type Summary struct {
+	// Type is always "summary"
+	Type string `json:"type"`
+
+	// Command that was run
+	Command string `json:"command"`
+
+	// Scanned is the number of files found
+	Scanned int `json:"scanned"`
+
+	// Excluded is the number of files filtered out
+	Excluded int `json:"excluded"`
+
+	// Processed is the number of files that succeeded
+	Processed int `json:"processed"`
+
+	// Errors is the number of files that failed
+	Errors int `json:"errors"`
+
+	// Size is the total output size in bytes
+	Size int64 `json:"size"`
+
+	// Duration of the run in milliseconds
+	Duration float64 `json:"duration_ms"`
+
+	// DryRun marks runs that did not write anything
+	DryRun bool `json:"dry_run,omitempty"`
+
+	// Shape is the placeholder shape of a redact run
+	Shape string `json:"shape,omitempty"`
+
+	// VerifyFailures is the number of outputs that failed --verify, set with --verify
+	VerifyFailures *int `json:"verify_failures,omitempty"`
+
+	// Stats are the detailed totals, set with --stats
+	Stats *Stats `json:"stats,omitempty"`
+}
+
+// Reporter prints events in the configured format. It is safe for concurrent use.
+type Reporter struct {
+	format   string
+	quiet    bool
+	classify func(error) string
+	out      io.Writer
+	errOut   io.Writer
+	events   []any
+	stats    *Stats
+	mu       sync.Mutex
+}
+
+// New creates a reporter writing to stdout. classify assigns error classes to failed files.
+// With stats, the reported files are totaled and the summary carries the Stats.
+func New(format string, quiet, stats bool, classify func(error) string) *Reporter {
+	if format == "" {
+		format = FormatText
+	}
+
+	reporter := &Reporter{format: format, quiet: quiet, classify: classify, out: os.Stdout, errOut: os.Stderr}
+
+	if stats {
+		reporter.stats = newStats()
+	}
+
+	return reporter
+}
+
+// Through routes all output through wrap, for example to keep it apart from a progress display.
+func (r *Reporter) Through(wrap func(io.Writer) io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.out, r.errOut = wrap(r.out), wrap(r.errOut)
+}
+
+// Structured reports whether events are printed as JSON.
+func (r *Reporter) Structured() bool {
+	return r.format != FormatText
+}
+
+// File reports a processed file.
+func (r *Reporter) File(event File) {
+	event.Type = "file"
+
+	if event.Err != nil {
+		event.Error = event.Err.Error()
+		event.ErrorClass = r.classify(event.Err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stats != nil {
+		r.stats.record(event)
+	}
+
+	if r.Structured() {
+		r.emit(event)
+
+		return
+	}
+
+	switch {
+	case event.ErrorClass == ClassCanceled, event.ErrorClass == ClassRolledBack:
+	case event.Err != nil:
+		fmt.Fprintf(r.errOut, "Error processing %q: %v\n", event.Input, event.Err)
+	case r.quiet:
+	case event.Secrets != nil:
+		fmt.Fprintf(r.out, "Processed %q -> %q (%d secrets)\n", event.Input, event.Output, *event.Secrets)
+	default:
+		fmt.Fprintf(r.out, "Processed %q -> %q\n", event.Input, event.Output)
+	}
+
+	if event.Deleted && !r.quiet {
+		fmt.Fprintf(r.out, "Deleted %q\n", event.Input)
+	}
+}
+
+// Skip counts files that were never started.
+func (r *Reporter) Skip(files int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stats != nil {
+		r.stats.Skipped += files
+	}
+}
+
+// Pattern reports a checked pattern.
+func (r *Reporter) Pattern(event Pattern) {
+	event.Type = "pattern"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.Structured() {
+		r.emit(event)
+
+		return
+	}
+
+	switch {
+	case event.Error == NoMatches:
+		fmt.Fprintf(r.errOut, "%s: %s — 0 files (ERROR)\n", event.Kind, event.Pattern)
+	case event.Error != "":
+		fmt.Fprintf(r.errOut, "%s: %s — %s\n", event.Kind, event.Pattern, event.Error)
+	case !r.quiet:
+		fmt.Fprintf(r.errOut, "%s: %s — %d files\n", event.Kind, event.Pattern, event.Matches)
+	}
+}
+
+// Summary ends the run. With FormatJSON, the collected events are printed together with the summary.
+// Text output has no summary; with stats, the human-readable stats are printed to stderr in every format.
+func (r *Reporter) Summary(summary Summary) {
+	summary.Type = "summary"
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.stats != nil {
+		r.stats.finish(time.Duration(summary.Duration * float64(time.Millisecond)))
+
+		summary.Stats = r.stats
+
+		defer WriteStats(r.errOut, summary)
+	}
+
+	switch r.format {
+	case FormatNDJSON:
+		r.emit(summary)
+	case FormatJSON:
+		events := r.events
+		if events == nil {
+			events = []any{}
+		}
+
+		document := struct {
+			Events  []any   `json:"events"`
+			Summary Summary `json:"summary"`
+		}{Events: events, Summary: summary}
+
+		out, err := json.MarshalIndent(document, "", "  ")
+		if err != nil {
+			fmt.Fprintf(r.errOut, "Error encoding report: %v\n", err)
+
+			return
+		}
+
+		fmt.Fprintln(r.out, string(out))
+	}
+}
+
+// Milliseconds converts a duration for the duration_ms fields.
+func Milliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / float64(time.Millisecond/time.Microsecond)
+}
+
+// emit prints or collects an event. The caller holds the lock.
+func (r *Reporter) emit(event any) {
+	if r.format == FormatJSON {
+		r.events = append(r.events, event)
+
+		return
+	}
+
+	out, err := json.Marshal(event)
+	if err != nil {
+		fmt.Fprintf(r.errOut, "Error encoding report: %v\n", err)
+
+		return
+	}
+
+	fmt.Fprintln(r.out, string(out))
+}
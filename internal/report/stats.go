@@ -0,0 +1,242 @@
+package report
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+)
+
+// slowest is the number of files listed as the slowest of a run.
+const slowest = 5
+
+// Stats are the totals of a run, reported with --stats.
+type Stats struct {
+	// InputBytes is the size of all processed inputs
+	InputBytes int64 `json:"input_bytes"`
+
+	// OutputBytes is the size of all written outputs
+	OutputBytes int64 `json:"output_bytes"`
+
+	// Overhead is OutputBytes minus InputBytes, negative when outputs are smaller
+	Overhead int64 `json:"overhead_bytes"`
+
+	// Ratio is OutputBytes divided by InputBytes
+	Ratio float64 `json:"ratio"`
+
+	// Throughput is InputBytes per second of the run
+	Throughput float64 `json:"throughput_bytes_per_sec"`
+
+	// Unchanged is the number of outputs that already held the same content
+	Unchanged int `json:"unchanged"`
+
+	// Skipped is the number of files never processed because of --fail-fast, an interrupt or a rolled back batch
+	Skipped int `json:"skipped"`
+
+	// ErrorClasses counts the failed files by error class
+	ErrorClasses map[string]int `json:"error_classes,omitempty"`
+
+	// Slowest are the files that took longest, slowest first
+	Slowest []Timing `json:"slowest"`
+
+	// Modes breaks the processed files down by the mode they were processed with
+	Modes map[string]Group `json:"modes"`
+
+	// Dirs breaks the processed files down by their top-level directory
+	Dirs map[string]Group `json:"dirs"`
+}
+
+// Timing is the duration of a single file.
+type Timing struct {
+	// Input file path
+	Input string `json:"input"`
+
+	// Duration of the processing in milliseconds
+	Duration float64 `json:"duration_ms"`
+}
+
+// Group totals the files sharing a mode or a directory.
+type Group struct {
+	// Files processed
+	Files int `json:"files"`
+
+	// InputBytes is the size of their inputs
+	InputBytes int64 `json:"input_bytes"`
+
+	// OutputBytes is the size of their outputs
+	OutputBytes int64 `json:"output_bytes"`
+}
+
+// add counts a file into the group.
+func (g Group) add(event File) Group {
+	g.Files++
+	g.InputBytes += event.InputSize
+	g.OutputBytes += event.OutputSize
+
+	return g
+}
+
+// newStats creates empty stats.
+func newStats() *Stats {
+	return &Stats{Slowest: []Timing{}, Modes: map[string]Group{}, Dirs: map[string]Group{}}
+}
+
+// record counts a reported file. Files that were never completed only count as skipped or failed.
+func (s *Stats) record(event File) {
+	switch {
+	case event.ErrorClass == ClassCanceled, event.ErrorClass == ClassRolledBack:
+		s.Skipped++
+
+		return
+	case event.Err != nil:
+		if s.ErrorClasses == nil {
+			s.ErrorClasses = map[string]int{}
+		}
+
+		s.ErrorClasses[event.ErrorClass]++
+
+		return
+	}
+
+	s.InputBytes += event.InputSize
+	s.OutputBytes += event.OutputSize
+
+	if event.Unchanged {
+		s.Unchanged++
+	}
+
+	if event.Mode != "" {
+		s.Modes[event.Mode] = s.Modes[event.Mode].add(event)
+	}
+
+	dir := topLevel(event.Input)
+	s.Dirs[dir] = s.Dirs[dir].add(event)
+
+	s.Slowest = append(s.Slowest, Timing{Input: event.Input, Duration: event.Duration})
+
+	slices.SortStableFunc(s.Slowest, func(a, b Timing) int { return cmp.Compare(b.Duration, a.Duration) })
+
+	s.Slowest = s.Slowest[:min(len(s.Slowest), slowest)]
+}
+
+// finish derives the totals that depend on the whole run.
+func (s *Stats) finish(duration time.Duration) {
+	s.Overhead = s.OutputBytes - s.InputBytes
+
+	if s.InputBytes > 0 {
+		s.Ratio = float64(s.OutputBytes) / float64(s.InputBytes)
+	}
+
+	if seconds := duration.Seconds(); seconds > 0 {
+		s.Throughput = float64(s.InputBytes) / seconds
+	}
+}
+
+// topLevel returns the first directory of a path, or "." for files in the current directory.
+func topLevel(path string) string {
+	path = filepath.ToSlash(filepath.Clean(path))
+
+	prefix := ""
+
+	if rest, ok := strings.CutPrefix(path, "/"); ok {
+		prefix, path = "/", rest
+	}
+
+	dir, _, found := strings.Cut(path, "/")
+	if !found {
+		return prefix + "."
+	}
+
+	return prefix + dir
+}
+
+// WriteStats prints the human-readable stats of a run. The details are only printed when summary.Stats is set.
+func WriteStats(out io.Writer, summary Summary) {
+	duration := time.Duration(summary.Duration * float64(time.Millisecond))
+
+	fmt.Fprintf(out, "\nStats\n")
+	fmt.Fprintf(out, "  Scanned:   %d\n", summary.Scanned)
+	fmt.Fprintf(out, "  Excluded:  %d\n", summary.Excluded)
+	fmt.Fprintf(out, "  Processed: %d\n", summary.Processed)
+	fmt.Fprintf(out, "  Errors:    %d\n", summary.Errors)
+	fmt.Fprintf(out, "  Size:      %s\n", bytes(summary.Size))
+	fmt.Fprintf(out, "  Duration:  %s\n", duration.Round(time.Millisecond))
+
+	if summary.Shape != "" {
+		fmt.Fprintf(out, "  Shape:     %s\n", summary.Shape)
+	}
+
+	if summary.VerifyFailures != nil {
+		fmt.Fprintf(out, "  Verify:    %d failed\n", *summary.VerifyFailures)
+	}
+
+	stats := summary.Stats
+	if stats == nil {
+		return
+	}
+
+	fmt.Fprintf(out, "  Input:     %s\n", bytes(stats.InputBytes))
+
+	if !summary.DryRun {
+		sign := "+"
+
+		if stats.Overhead < 0 {
+			sign = "-"
+		}
+
+		fmt.Fprintf(out, "  Output:    %s\n", bytes(stats.OutputBytes))
+		fmt.Fprintf(out, "  Overhead:  %s%s (ratio %.2f)\n", sign, bytes(max(stats.Overhead, -stats.Overhead)), stats.Ratio)
+		fmt.Fprintf(out, "  Speed:     %s/s\n", bytes(int64(stats.Throughput)))
+		fmt.Fprintf(out, "  Unchanged: %d\n", stats.Unchanged)
+		fmt.Fprintf(out, "  Skipped:   %d\n", stats.Skipped)
+	}
+
+	for _, class := range slices.Sorted(maps.Keys(stats.ErrorClasses)) {
+		fmt.Fprintf(out, "  Errors (%s): %d\n", class, stats.ErrorClasses[class])
+	}
+
+	if len(stats.Slowest) > 0 && !summary.DryRun {
+		fmt.Fprintf(out, "  Slowest:\n")
+
+		for _, timing := range stats.Slowest {
+			fmt.Fprintf(out, "    %-10s %s\n",
+				time.Duration(timing.Duration*float64(time.Millisecond)).Round(time.Microsecond), timing.Input)
+		}
+	}
+
+	writeGroups(out, "Modes", stats.Modes, summary.DryRun)
+	writeGroups(out, "Dirs", stats.Dirs, summary.DryRun)
+}
+
+// writeGroups prints a breakdown, sorted by name.
+func writeGroups(out io.Writer, title string, groups map[string]Group, dryRun bool) {
+	if len(groups) == 0 {
+		return
+	}
+
+	fmt.Fprintf(out, "  %s:\n", title)
+
+	for _, name := range slices.Sorted(maps.Keys(groups)) {
+		group := groups[name]
+
+		if dryRun {
+			fmt.Fprintf(out, "    %-16s %d files, %s\n", name, group.Files, bytes(group.InputBytes))
+
+			continue
+		}
+
+		fmt.Fprintf(out, "    %-16s %d files, %s -> %s\n",
+			name, group.Files, bytes(group.InputBytes), bytes(group.OutputBytes))
+	}
+}
+
+// bytes formats a non-negative byte count.
+func bytes(size int64) string {
+	return humanize.IBytes(uint64(max(0, size))) //nolint:gosec // clamped to non-negative
+}
@@ -0,0 +1,49 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/tidwall/jsonc"
+)
+
+// LoadRules reads a JSONC file with secret rules.
+// Entries are either rule objects or bare patterns, which are named after themselves.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path) //nolint:gosec // path is from user-supplied config
+	if err != nil {
+		return nil, fmt.Errorf("reading rules file %q: %w", path, err)
+	}
+
+	clean := jsonc.ToJSONInPlace(data)
+
+	var entries []json.RawMessage
+	if err := json.Unmarshal(clean, &entries); err != nil {
+		return nil, fmt.Errorf("parsing rules file %q: %w", path, err)
+	}
+
+	rules := make([]Rule, 0, len(entries))
+
+	for _, entry := range entries {
+		var pattern string
+		if err := json.Unmarshal(entry, &pattern); err == nil {
+			rules = append(rules, Rule{Name: pattern, Pattern: pattern})
+
+			continue
+		}
+
+		var rule Rule
+		if err := json.Unmarshal(entry, &rule); err != nil {
+			return nil, fmt.Errorf("parsing rules file %q: %w", path, err)
+		}
+
+		if rule.Name == "" {
+			rule.Name = rule.Pattern
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
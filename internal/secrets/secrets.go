@@ -0,0 +1,200 @@
+// Package secrets finds secrets inside files using regex rules and built-in detectors,
+// so that only the matched spans need to be redacted.
+package secrets
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+)
+
+// Rule is a named regular expression matching a secret.
+// If the expression has a group named "secret", only that group is redacted.
+type Rule struct {
+	// Name identifies the rule in reports
+	Name string `json:"name"`
+
+	// Pattern is the regular expression to match
+	Pattern string `json:"pattern"`
+}
+
+// Finding is a span of data recognized as a secret.
+type Finding struct {
+	// Rule is the name of the rule or detector that matched
+	Rule string
+
+	// Start and End delimit the secret in the scanned data
+	Start, End int
+}
+
+// Builtins are the built-in detectors for common secret formats.
+//
+//nolint:gochecknoglobals // immutable rule table
+var Builtins = []Rule{
+	{Name: "aws-access-key-id", Pattern: `\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}\b`},
+	{
+		Name:    "aws-secret-access-key",
+		Pattern: `(?i)aws.{0,20}?(?:secret|key).{0,20}?[:=]\s*["']?(?P<secret>[A-Za-z0-9/+=]{40})\b`,
+	},
+	{
+		Name:    "private-key",
+		Pattern: `-----BEGIN [A-Z ]*PRIVATE KEY(?: BLOCK)?-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY(?: BLOCK)?-----`,
+	},
+	{Name: "jwt", Pattern: `\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`},
+}
+
+// High-entropy detection thresholds, in bits per character.
+// Hex strings shorter than a 256-bit key, such as commit hashes and UUIDs, are not flagged.
+const (
+	entropyMinLength    = 20
+	entropyHexMinLength = 64
+	entropyBase64       = 4.5
+	entropyHex          = 3.0
+)
+
+var (
+	//nolint:gochecknoglobals // compiled once
+	tokenPattern = regexp.MustCompile(`[A-Za-z0-9+/=_-]{20,}`)
+	//nolint:gochecknoglobals // compiled once
+	hexPattern = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+)
+
+// Scanner finds secrets using compiled rules and, optionally, entropy analysis.
+type Scanner struct {
+	rules   []*regexp.Regexp
+	names   []string
+	entropy bool
+}
+
+// NewScanner compiles the given rules. With builtins set, the built-in detectors
+// and high-entropy string detection are added.
+func NewScanner(rules []Rule, builtins bool) (*Scanner, error) {
+	if builtins {
+		rules = append(append([]Rule{}, Builtins...), rules...)
+	}
+
+	scanner := &Scanner{entropy: builtins}
+
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("compiling rule %q: %w", rule.Name, err)
+		}
+
+		scanner.rules = append(scanner.rules, re)
+		scanner.names = append(scanner.names, rule.Name)
+	}
+
+	return scanner, nil
+}
+
+// Scan returns the non-overlapping secret spans in data, ordered by position.
+// Overlapping matches are merged and attributed to the earliest rule.
+func (s *Scanner) Scan(data []byte) []Finding {
+	var findings []Finding
+
+	for i, re := range s.rules {
+		group := re.SubexpIndex("secret")
+
+		for _, match := range re.FindAllSubmatchIndex(data, -1) {
+			start, end := match[0], match[1]
+
+			if group > 0 && match[2*group] >= 0 {
+				start, end = match[2*group], match[2*group+1]
+			}
+
+			findings = append(findings, Finding{Rule: s.names[i], Start: start, End: end})
+		}
+	}
+
+	if s.entropy {
+		for _, match := range tokenPattern.FindAllIndex(data, -1) {
+			if highEntropy(data[match[0]:match[1]]) {
+				findings = append(findings, Finding{Rule: "high-entropy", Start: match[0], End: match[1]})
+			}
+		}
+	}
+
+	return merge(findings)
+}
+
+// Redact replaces every secret span in data with replacement.
+func (s *Scanner) Redact(data []byte, replacement string) ([]byte, []Finding) {
+	findings := s.Scan(data)
+	if len(findings) == 0 {
+		return data, nil
+	}
+
+	out := make([]byte, 0, len(data))
+	last := 0
+
+	for _, finding := range findings {
+		out = append(out, data[last:finding.Start]...)
+		out = append(out, replacement...)
+		last = finding.End
+	}
+
+	return append(out, data[last:]...), findings
+}
+
+// merge sorts findings and folds overlapping spans into the first one.
+func merge(findings []Finding) []Finding {
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Start < findings[j].Start
+	})
+
+	var merged []Finding
+
+	for _, finding := range findings {
+		if n := len(merged); n > 0 && finding.Start < merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, finding.End)
+
+			continue
+		}
+
+		merged = append(merged, finding)
+	}
+
+	return merged
+}
+
+// highEntropy reports whether a token looks random enough to be a secret.
+func highEntropy(token []byte) bool {
+	if len(token) < entropyMinLength {
+		return false
+	}
+
+	threshold := entropyBase64
+	if hexPattern.Match(token) {
+		if len(token) < entropyHexMinLength {
+			return false
+		}
+
+		threshold = entropyHex
+	}
+
+	return shannon(token) >= threshold
+}
+
+// shannon computes the Shannon entropy of data in bits per byte.
+func shannon(data []byte) float64 {
+	var counts [256]int
+
+	for _, b := range data {
+		counts[b]++
+	}
+
+	var entropy float64
+
+	for _, count := range counts {
+		if count == 0 {
+			continue
+		}
+
+		p := float64(count) / float64(len(data))
+		entropy -= p * math.Log2(p)
+	}
+
+	return entropy
+}
@@ -0,0 +1,6 @@
+// Package structured locates and rewrites scalar values inside JSON, YAML, TOML and .env documents.
+//
+// Documents are never re-serialized. Each scalar is recorded together with the byte span it occupies
+// in the source, and edits splice replacement literals into those spans. Keys, comments, ordering and
+// formatting of everything that is not edited are left untouched.
+package structured
@@ -0,0 +1,223 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrUnsupported indicates that a file is not in a supported structured format.
+var ErrUnsupported = errors.New("unsupported structured format")
+
+// Format identifies the syntax of a document.
+type Format string
+
+const (
+	// JSON documents, comments and trailing commas are tolerated.
+	JSON Format = "json"
+	// YAML documents.
+	YAML Format = "yaml"
+	// TOML documents.
+	TOML Format = "toml"
+	// Env documents in dotenv syntax.
+	Env Format = "env"
+)
+
+// Kind is the type of a scalar value.
+type Kind byte
+
+const (
+	// String values carry their decoded content.
+	String Kind = 's'
+	// Number values carry their literal source text.
+	Number Kind = 'n'
+	// Bool values carry "true" or "false".
+	Bool Kind = 'b'
+	// Null values carry their literal source text.
+	Null Kind = 'z'
+	// Raw values carry literal source text of any other scalar type, such as TOML dates.
+	Raw Kind = 'r'
+)
+
+// Leaf is a scalar value found in a document.
+type Leaf struct {
+	// Path is the dotted location of the value, with sequence indices as numbers
+	// and dots or backslashes within a key escaped with a backslash
+	Path string
+
+	// Kind is the type of the value
+	Kind Kind
+
+	// Value is the decoded string content, or the literal text for non-string kinds
+	Value string
+
+	// start and end delimit the literal in the source
+	start, end int
+}
+
+// Edit replaces the literal of a leaf with a new value.
+type Edit struct {
+	// Leaf to replace
+	Leaf Leaf
+
+	// Kind of the new value
+	Kind Kind
+
+	// Value is the new string content, or literal text for non-string kinds
+	Value string
+
+	// Literal, when set, is spliced in verbatim instead of encoding Value
+	Literal string
+}
+
+// Document is a parsed structured file.
+type Document struct {
+	format Format
+	source []byte
+	leaves []Leaf
+}
+
+// Detect returns the format of a file based on its name.
+func Detect(name string) (Format, error) {
+	base := filepath.Base(name)
+
+	switch ext := strings.ToLower(filepath.Ext(base)); {
+	case ext == ".json" || ext == ".jsonc":
+		return JSON, nil
+	case ext == ".yaml" || ext == ".yml":
+		return YAML, nil
+	case ext == ".toml":
+		return TOML, nil
+	case ext == ".env" || base == ".env" || strings.HasPrefix(base, ".env."):
+		return Env, nil
+	default:
+		return "", fmt.Errorf("%w: %q", ErrUnsupported, name)
+	}
+}
+
+// Parse parses data in the format implied by name.
+func Parse(name string, data []byte) (*Document, error) {
+	format, err := Detect(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var leaves []Leaf
+
+	switch format {
+	case JSON:
+		leaves, err = parseJSON(data)
+	case YAML:
+		leaves, err = parseYAML(data)
+	case TOML:
+		leaves, err = parseTOML(data)
+	case Env:
+		leaves, err = parseEnv(data)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", format, err)
+	}
+
+	return &Document{format: format, source: data, leaves: leaves}, nil
+}
+
+// Format returns the format of the document.
+func (d *Document) Format() Format {
+	return d.format
+}
+
+// Leaves returns all scalar values in source order.
+func (d *Document) Leaves() []Leaf {
+	return d.leaves
+}
+
+// Lookup returns the leaf at the given dotted path, in which dots that are part of a key are escaped as `\.`.
+func (d *Document) Lookup(path string) (Leaf, bool) {
+	for _, leaf := range d.leaves {
+		if leaf.Path == path {
+			return leaf, true
+		}
+	}
+
+	return Leaf{}, false
+}
+
+// Literal returns the source text of a leaf, including any quotes.
+func (d *Document) Literal(leaf Leaf) string {
+	return string(d.source[leaf.start:leaf.end])
+}
+
+// Apply returns the source with all edits spliced in.
+// Edits are independent of each other and may be given in any order.
+func (d *Document) Apply(edits []Edit) ([]byte, error) {
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].Leaf.start < edits[j].Leaf.start
+	})
+
+	out := make([]byte, 0, len(d.source))
+	last := 0
+
+	for _, edit := range edits {
+		if edit.Leaf.start < last {
+			return nil, fmt.Errorf("overlapping edits at %q", edit.Leaf.Path)
+		}
+
+		literal := edit.Literal
+
+		if literal == "" {
+			var err error
+
+			if literal, err = d.literal(edit.Kind, edit.Value); err != nil {
+				return nil, fmt.Errorf("encoding %q: %w", edit.Leaf.Path, err)
+			}
+		}
+
+		out = append(out, d.source[last:edit.Leaf.start]...)
+		out = append(out, literal...)
+		last = edit.Leaf.end
+	}
+
+	return append(out, d.source[last:]...), nil
+}
+
+// literal encodes a value as source text for the document format.
+func (d *Document) literal(kind Kind, value string) (string, error) {
+	if kind != String {
+		return value, nil
+	}
+
+	if d.format == Env {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value) + `"`, nil
+	}
+
+	// JSON string literals are also valid double-quoted YAML and TOML basic strings.
+	var buf bytes.Buffer
+
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(value); err != nil {
+		return "", fmt.Errorf("quoting value: %w", err)
+	}
+
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// joinPath appends a segment to a dotted path, escaping dots and backslashes within the segment.
+func joinPath(parent, segment string) string {
+	segment = pathEscaper.Replace(segment)
+
+	if parent == "" {
+		return segment
+	}
+
+	return parent + "." + segment
+}
+
+// pathEscaper escapes the characters of a segment that would otherwise be read as path syntax.
+var pathEscaper = strings.NewReplacer(`\`, `\\`, ".", `\.`)
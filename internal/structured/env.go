@@ -0,0 +1,114 @@
+package structured
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// parseEnv collects the values of a dotenv document.
+// Every value is a string; blank lines, comments and "export" prefixes are skipped.
+func parseEnv(data []byte) ([]Leaf, error) {
+	var leaves []Leaf
+
+	offset := 0
+
+	for number, line := range bytes.SplitAfter(data, []byte("\n")) {
+		lineStart := offset
+		offset += len(line)
+
+		text := strings.TrimRight(string(line), "\r\n")
+		trimmed := strings.TrimLeft(text, " \t")
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		pos := lineStart + len(text) - len(trimmed)
+
+		if rest, ok := strings.CutPrefix(trimmed, "export "); ok {
+			pos += len(trimmed) - len(rest)
+			trimmed = rest
+		}
+
+		key, _, found := strings.Cut(trimmed, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: missing '='", number+1)
+		}
+
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("line %d: empty key", number+1)
+		}
+
+		valueStart := pos + strings.Index(trimmed, "=") + 1
+
+		raw := strings.TrimLeft(string(data[valueStart:lineStart+len(text)]), " \t")
+		valueStart = lineStart + len(text) - len(raw)
+
+		value, length, err := parseEnvValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number+1, err)
+		}
+
+		leaves = append(leaves, Leaf{
+			Path:  joinPath("", key),
+			Kind:  String,
+			Value: value,
+			start: valueStart,
+			end:   valueStart + length,
+		})
+	}
+
+	return leaves, nil
+}
+
+// parseEnvValue decodes a raw value and returns the length of its literal.
+func parseEnvValue(raw string) (string, int, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		var builder strings.Builder
+
+		for i := 1; i < len(raw); i++ {
+			switch raw[i] {
+			case '\\':
+				if i+1 < len(raw) {
+					i++
+
+					switch raw[i] {
+					case 'n':
+						builder.WriteByte('\n')
+					case 't':
+						builder.WriteByte('\t')
+					default:
+						builder.WriteByte(raw[i])
+					}
+				}
+			case '"':
+				return builder.String(), i + 1, nil
+			default:
+				builder.WriteByte(raw[i])
+			}
+		}
+
+		return "", 0, errors.New("unterminated double-quoted value")
+	case strings.HasPrefix(raw, "'"):
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", 0, errors.New("unterminated single-quoted value")
+		}
+
+		return raw[1 : end+1], end + 2, nil
+	default:
+		value := raw
+
+		if idx := strings.Index(value, " #"); idx >= 0 {
+			value = value[:idx]
+		}
+
+		value = strings.TrimRight(value, " \t")
+
+		return value, len(value), nil
+	}
+}
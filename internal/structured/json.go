@@ -0,0 +1,127 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/tidwall/jsonc"
+)
+
+// parseJSON collects the scalar values of a JSON document.
+// Comments and trailing commas are blanked out first; this keeps all byte offsets intact.
+func parseJSON(data []byte) ([]Leaf, error) {
+	clean := jsonc.ToJSON(data)
+
+	decoder := json.NewDecoder(bytes.NewReader(clean))
+	decoder.UseNumber()
+
+	parser := &jsonParser{decoder: decoder, data: clean}
+
+	if err := parser.value(""); err != nil {
+		return nil, err
+	}
+
+	if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+		return nil, errors.New("unexpected data after top-level value")
+	}
+
+	return parser.leaves, nil
+}
+
+// jsonParser walks a token stream, tracking the source offset of every token.
+type jsonParser struct {
+	decoder *json.Decoder
+	data    []byte
+	leaves  []Leaf
+}
+
+// token reads the next token and returns the offset where its literal starts.
+func (p *jsonParser) token() (json.Token, int, error) {
+	start := int(p.decoder.InputOffset())
+
+	tok, err := p.decoder.Token()
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading token: %w", err)
+	}
+
+	// The decoder position sits before separators and whitespace preceding the token.
+	for start < len(p.data) && bytes.IndexByte([]byte(" \t\r\n:,"), p.data[start]) >= 0 {
+		start++
+	}
+
+	return tok, start, nil
+}
+
+// value parses one value at path.
+//
+//nolint:cyclop // one case per JSON value type
+func (p *jsonParser) value(path string) error {
+	tok, start, err := p.token()
+	if err != nil {
+		return err
+	}
+
+	end := int(p.decoder.InputOffset())
+
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			return p.object(path)
+		case '[':
+			return p.array(path)
+		default:
+			return fmt.Errorf("unexpected %q", tok)
+		}
+	case string:
+		p.leaves = append(p.leaves, Leaf{Path: path, Kind: String, Value: tok, start: start, end: end})
+	case json.Number:
+		p.leaves = append(p.leaves, Leaf{Path: path, Kind: Number, Value: tok.String(), start: start, end: end})
+	case bool:
+		p.leaves = append(p.leaves, Leaf{Path: path, Kind: Bool, Value: strconv.FormatBool(tok), start: start, end: end})
+	case nil:
+		p.leaves = append(p.leaves, Leaf{Path: path, Kind: Null, Value: "null", start: start, end: end})
+	}
+
+	return nil
+}
+
+// object parses the members of an object whose opening brace was consumed.
+func (p *jsonParser) object(path string) error {
+	for p.decoder.More() {
+		tok, _, err := p.token()
+		if err != nil {
+			return err
+		}
+
+		key, ok := tok.(string)
+		if !ok {
+			return fmt.Errorf("expected object key, got %v", tok)
+		}
+
+		if err := p.value(joinPath(path, key)); err != nil {
+			return err
+		}
+	}
+
+	_, _, err := p.token()
+
+	return err
+}
+
+// array parses the elements of an array whose opening bracket was consumed.
+func (p *jsonParser) array(path string) error {
+	for index := 0; p.decoder.More(); index++ {
+		if err := p.value(joinPath(path, strconv.Itoa(index))); err != nil {
+			return err
+		}
+	}
+
+	_, _, err := p.token()
+
+	return err
+}
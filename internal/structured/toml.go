@@ -0,0 +1,114 @@
+package structured
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/pelletier/go-toml/v2/unstable"
+)
+
+// parseTOML collects the scalar values of a TOML document.
+// Array tables are indexed by their order of appearance.
+func parseTOML(data []byte) ([]Leaf, error) {
+	walker := &tomlWalker{arrays: make(map[string]int)}
+
+	walker.parser.Reset(data)
+
+	var table string
+
+	for walker.parser.NextExpression() {
+		expr := walker.parser.Expression()
+
+		switch expr.Kind {
+		case unstable.Table:
+			table = tomlKey(expr.Key(), "")
+		case unstable.ArrayTable:
+			key := tomlKey(expr.Key(), "")
+			table = joinPath(key, strconv.Itoa(walker.arrays[key]))
+			walker.arrays[key]++
+		case unstable.KeyValue:
+			if err := walker.keyValue(expr, table); err != nil {
+				return nil, err
+			}
+		default:
+		}
+	}
+
+	if err := walker.parser.Error(); err != nil {
+		return nil, fmt.Errorf("parsing toml: %w", err)
+	}
+
+	return walker.leaves, nil
+}
+
+// tomlWalker collects leaves from TOML expressions.
+type tomlWalker struct {
+	parser unstable.Parser
+	arrays map[string]int
+	leaves []Leaf
+}
+
+// keyValue records the value of a key/value expression relative to table.
+func (w *tomlWalker) keyValue(node *unstable.Node, table string) error {
+	return w.value(node.Value(), tomlKey(node.Key(), table))
+}
+
+// value records a value node at path, descending into arrays and inline tables.
+func (w *tomlWalker) value(node *unstable.Node, path string) error {
+	var kind Kind
+
+	switch node.Kind {
+	case unstable.Array:
+		index := 0
+
+		for children := node.Children(); children.Next(); index++ {
+			if err := w.value(children.Node(), joinPath(path, strconv.Itoa(index))); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case unstable.InlineTable:
+		for children := node.Children(); children.Next(); {
+			if err := w.keyValue(children.Node(), path); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case unstable.String:
+		kind = String
+	case unstable.Integer, unstable.Float:
+		kind = Number
+	case unstable.Bool:
+		kind = Bool
+	default:
+		kind = Raw
+	}
+
+	raw := node.Raw
+	if raw.Length == 0 {
+		raw = w.parser.Range(node.Data)
+	}
+
+	start := int(raw.Offset)
+	end := start + int(raw.Length)
+
+	value := string(w.parser.Raw(raw))
+	if kind == String {
+		value = string(node.Data)
+	}
+
+	w.leaves = append(w.leaves, Leaf{Path: path, Kind: kind, Value: value, start: start, end: end})
+
+	return nil
+}
+
+// tomlKey joins the parts of a possibly dotted key onto prefix.
+func tomlKey(parts unstable.Iterator, prefix string) string {
+	for parts.Next() {
+		prefix = joinPath(prefix, string(parts.Node().Data))
+	}
+
+	return prefix
+}
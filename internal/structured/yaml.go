@@ -0,0 +1,198 @@
+package structured
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// parseYAML collects the scalar values of a YAML stream.
+// Paths of documents after the first are prefixed with their index.
+func parseYAML(data []byte) ([]Leaf, error) {
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing yaml: %w", err)
+	}
+
+	walker := &yamlWalker{data: data, lineStarts: []int{0}}
+
+	for i, b := range data {
+		if b == '\n' {
+			walker.lineStarts = append(walker.lineStarts, i+1)
+		}
+	}
+
+	for index, doc := range file.Docs {
+		prefix := ""
+		if index > 0 {
+			prefix = strconv.Itoa(index)
+		}
+
+		if err := walker.walk(doc.Body, prefix); err != nil {
+			return nil, err
+		}
+	}
+
+	return walker.leaves, nil
+}
+
+// yamlWalker collects leaves from a YAML AST.
+type yamlWalker struct {
+	data       []byte
+	lineStarts []int
+	leaves     []Leaf
+}
+
+// walk visits node at path.
+//
+//nolint:cyclop // one case per YAML node type
+func (w *yamlWalker) walk(node ast.Node, path string) error {
+	switch node := node.(type) {
+	case nil, *ast.AliasNode, *ast.CommentGroupNode, *ast.CommentNode:
+		return nil
+	case *ast.MappingNode:
+		for _, value := range node.Values {
+			if err := w.walk(value, path); err != nil {
+				return err
+			}
+		}
+	case *ast.MappingValueNode:
+		if node.Key.IsMergeKey() {
+			return nil
+		}
+
+		return w.walk(node.Value, joinPath(path, node.Key.GetToken().Value))
+	case *ast.SequenceNode:
+		for index, value := range node.Values {
+			if err := w.walk(value, joinPath(path, strconv.Itoa(index))); err != nil {
+				return err
+			}
+		}
+	case *ast.AnchorNode:
+		return w.walk(node.Value, path)
+	case *ast.TagNode:
+		return w.tagged(node, path)
+	case *ast.LiteralNode:
+		return w.literal(node, path)
+	case *ast.StringNode:
+		return w.scalar(node.GetToken(), path, String, node.Value)
+	case *ast.IntegerNode, *ast.FloatNode, *ast.InfinityNode, *ast.NanNode:
+		return w.scalar(node.GetToken(), path, Number, "")
+	case *ast.BoolNode:
+		return w.scalar(node.GetToken(), path, Bool, strconv.FormatBool(node.Value))
+	case *ast.NullNode:
+		return w.scalar(node.GetToken(), path, Null, "")
+	default:
+		return fmt.Errorf("unsupported yaml node %s at %q", node.Type(), path)
+	}
+
+	return nil
+}
+
+// scalar records a single-token scalar. An empty value means the literal text is the value.
+func (w *yamlWalker) scalar(tok *token.Token, path string, kind Kind, value string) error {
+	start, end, err := w.locate(tok, -1)
+
+	switch {
+	case err != nil && kind == Null:
+		// Implicit nulls such as "key:" have no literal to replace.
+		return nil
+	case err != nil:
+		return fmt.Errorf("locating %q: %w", path, err)
+	}
+
+	if value == "" && kind != String {
+		value = string(w.data[start:end])
+	}
+
+	w.leaves = append(w.leaves, Leaf{Path: path, Kind: kind, Value: value, start: start, end: end})
+
+	return nil
+}
+
+// literal records a block scalar, spanning from its indicator to the end of its content.
+func (w *yamlWalker) literal(node *ast.LiteralNode, path string) error {
+	start, _, err := w.locate(node.Start, -1)
+	if err != nil {
+		return fmt.Errorf("locating %q: %w", path, err)
+	}
+
+	_, end, err := w.locate(node.Value.GetToken(), start)
+	if err != nil {
+		return fmt.Errorf("locating %q: %w", path, err)
+	}
+
+	w.leaves = append(w.leaves, Leaf{Path: path, Kind: String, Value: node.Value.Value, start: start, end: end})
+
+	return nil
+}
+
+// tagged records an explicitly tagged scalar verbatim, tag included.
+// Tagged collections are walked as usual.
+func (w *yamlWalker) tagged(node *ast.TagNode, path string) error {
+	if _, ok := node.Value.(ast.ScalarNode); !ok {
+		return w.walk(node.Value, path)
+	}
+
+	count := len(w.leaves)
+
+	if err := w.walk(node.Value, path); err != nil {
+		return err
+	}
+
+	if len(w.leaves) == count {
+		return nil
+	}
+
+	start, _, err := w.locate(node.Start, -1)
+	if err != nil {
+		return fmt.Errorf("locating %q: %w", path, err)
+	}
+
+	leaf := &w.leaves[len(w.leaves)-1]
+	leaf.start = start
+	leaf.Kind = Raw
+	leaf.Value = string(w.data[start:leaf.end])
+
+	return nil
+}
+
+// locate finds the source span of a token.
+// The search starts at the token's line and column, or at from when it is not negative.
+// Starting at the column keeps an equal literal earlier on the line, such as the key, from matching.
+func (w *yamlWalker) locate(tok *token.Token, from int) (int, int, error) {
+	raw := strings.TrimSpace(tok.Origin)
+	if raw == "" {
+		raw = tok.Value
+	}
+
+	if from < 0 {
+		line := tok.Position.Line - 1
+		if line < 0 || line >= len(w.lineStarts) {
+			return 0, 0, fmt.Errorf("line %d out of range", tok.Position.Line)
+		}
+
+		from = w.lineStarts[line]
+
+		// Columns count runes from 1.
+		for column := 1; column < tok.Position.Column && from < len(w.data) && w.data[from] != '\n'; column++ {
+			_, size := utf8.DecodeRune(w.data[from:])
+			from += size
+		}
+	}
+
+	idx := bytes.Index(w.data[from:], []byte(raw))
+	if idx < 0 {
+		return 0, 0, fmt.Errorf("literal %q not found", raw)
+	}
+
+	start := from + idx
+
+	return start, start + len(raw), nil
+}
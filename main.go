@@ -15,6 +15,8 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/idelchi/gonc/internal/encryption"
+	"github.com/idelchi/gonc/internal/logic"
 	"github.com/idelchi/gonc/internal/parse"
 )
 
@@ -22,12 +24,18 @@ import (
 var version = "unknown - unofficial & generated by unknown"
 
 // main is the entry point of the application.
+// The exit code classifies the failure, see logic.ExitCode.
 func main() {
-	if err := parse.Execute(version); err != nil {
+	err := parse.Execute(version)
+
+	// Keys are wiped here, as os.Exit skips deferred functions.
+	encryption.WipeKeys()
+
+	if err != nil {
 		fmt.Fprintln(os.Stderr, err)
 
-		os.Exit(1)
+		os.Exit(logic.ExitCode(err))
 	}
 
-	os.Exit(0)
+	os.Exit(logic.ExitOK)
 }
@@ -0,0 +1,127 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+
+echo "🧪 Testing a failing batch is rolled back"
+
+mkdir -p tree
+echo "a" >tree/a.txt
+echo "b" >tree/b.txt
+echo "c" >tree/c.txt
+echo "stale" >tree/c.txt.enc
+if gonc -q --key-file key --atomic-batch --delete encrypt tree 2>/dev/null; then
+  echo "❌ test: A batch with a failing file should fail" && exit 1
+fi
+[[ ! -e tree/a.txt.enc && ! -e tree/b.txt.enc ]] || (echo '❌ test: No outputs should be written' && exit 1)
+[[ -f tree/a.txt && -f tree/b.txt && -f tree/c.txt ]] || (echo '❌ test: No inputs should be deleted' && exit 1)
+[[ "$(cat tree/c.txt.enc)" == "stale" ]] || (echo '❌ test: Existing outputs should be kept' && exit 1)
+[[ -z "$(find tree -name '.tmp-*' -o -name '.prev-*')" ]] || (echo '❌ test: Temporary files should be removed' && exit 1)
+[[ ! -e .gonc-journal.json ]] || (echo '❌ test: The journal should be removed' && exit 1)
+echo "✅ Failing batch is rolled back"
+
+echo "🧪 Testing a successful batch is committed"
+
+rm tree/c.txt.enc
+OUT=$(gonc --key-file key --atomic-batch --delete encrypt tree 2>&1)
+[[ $OUT == *'Deleted "tree/c.txt"'* ]] || (echo '❌ test: Inputs should be deleted after the commit' && exit 1)
+[[ -f tree/a.txt.enc && -f tree/b.txt.enc && -f tree/c.txt.enc ]] || (echo '❌ test: All outputs should be written' && exit 1)
+[[ ! -e tree/a.txt ]] || (echo '❌ test: Inputs should be deleted' && exit 1)
+gonc -q --key-file key --atomic-batch --delete decrypt tree
+[[ "$(cat tree/b.txt)" == "b" ]] || (echo '❌ test: Batch decryption should work' && exit 1)
+echo "✅ Successful batch is committed"
+
+echo "🧪 Testing --backup keeps replaced outputs"
+
+echo "new" >tree/a.txt
+echo "old" >tree/a.txt.enc
+gonc -q --atomic-batch --backup redact tree/a.txt
+[[ "$(cat tree/a.txt.enc.bak)" == "old" ]] || (echo '❌ test: Replaced output should be backed up' && exit 1)
+[[ "$(cat tree/a.txt.enc)" == "<REDACTED>" ]] || (echo '❌ test: Output should be replaced' && exit 1)
+echo "✅ --backup keeps replaced outputs"
+
+echo "🧪 Testing an interrupted commit is finished"
+
+mkdir -p next
+echo "n" >next/n.txt
+echo "x" >x.txt
+echo "y" >y.txt
+echo "committed" >x.txt.enc
+echo "staged" >.tmp-1
+cat >.gonc-journal.json <<'JSON'
+{
+  "state": "commit",
+  "delete": true,
+  "entries": [
+    {"input": "x.txt", "output": "x.txt.enc", "temp": ".tmp-0", "previous": ".prev-0"},
+    {"input": "y.txt", "output": "y.txt.enc", "temp": ".tmp-1", "previous": ".prev-1"}
+  ]
+}
+JSON
+OUT=$(gonc --key-file key encrypt next/n.txt 2>&1)
+[[ $OUT == *"Recovered interrupted batch"*"finished"* ]] || (echo '❌ test: The interrupted commit should be reported' && exit 1)
+[[ "$(cat y.txt.enc)" == "staged" ]] || (echo '❌ test: Staged outputs should be moved into place' && exit 1)
+[[ ! -e x.txt && ! -e y.txt && ! -e .gonc-journal.json ]] || (echo '❌ test: The commit should be finished' && exit 1)
+echo "✅ Interrupted commit is finished"
+
+echo "🧪 Testing an incomplete commit is rolled back"
+
+echo "x" >x.txt
+echo "previous" >.prev-0
+echo "partial" >x.txt.enc
+cat >.gonc-journal.json <<'JSON'
+{
+  "state": "commit",
+  "delete": true,
+  "entries": [
+    {"input": "x.txt", "output": "x.txt.enc", "temp": ".tmp-0", "previous": ".prev-0"},
+    {"input": "z.txt", "output": "z.txt.enc", "temp": ".tmp-2", "previous": ".prev-2"}
+  ]
+}
+JSON
+OUT=$(gonc --key-file key --force encrypt next/n.txt 2>&1)
+[[ $OUT == *"rolled back"* ]] || (echo '❌ test: The rollback should be reported' && exit 1)
+[[ "$(cat x.txt.enc)" == "previous" && -f x.txt ]] || (echo '❌ test: The previous output should be restored' && exit 1)
+[[ ! -e .prev-0 && ! -e .gonc-journal.json ]] || (echo '❌ test: The rollback should be finished' && exit 1)
+echo "✅ Incomplete commit is rolled back"
+
+echo "🧪 Testing a commit whose staged file was lost is rolled back"
+
+echo "x" >x.txt
+echo "mine" >x.txt.enc
+echo "staged" >.tmp-1
+cat >.gonc-journal.json <<'JSON'
+{
+  "state": "commit",
+  "delete": true,
+  "entries": [
+    {"input": "x.txt", "output": "x.txt.enc", "temp": ".tmp-0", "previous": ".prev-0", "existed": true},
+    {"input": "y.txt", "output": "y.txt.enc", "temp": ".tmp-1", "previous": ".prev-1"}
+  ]
+}
+JSON
+OUT=$(gonc --key-file key --force encrypt next/n.txt 2>&1)
+[[ $OUT == *"rolled back"* ]] || (echo '❌ test: The rollback should be reported' && exit 1)
+[[ "$(cat x.txt.enc)" == "mine" && -f x.txt ]] || (echo '❌ test: The existing output and its input should be kept' && exit 1)
+[[ ! -e .tmp-1 && ! -e .gonc-journal.json ]] || (echo '❌ test: The rollback should be finished' && exit 1)
+echo "✅ Existing outputs survive a lost staged file"
+
+echo "✨ ALL BATCH TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
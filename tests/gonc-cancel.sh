@@ -0,0 +1,51 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+
+echo "🧪 Testing --fail-fast"
+
+mkdir -p tree
+for name in a b c d; do echo "$name" >"tree/$name.txt"; done
+echo "stale" >tree/a.txt.enc
+OUT=$(gonc --key-file key -j 1 --fail-fast encrypt tree/*.txt 2>&1 || true)
+[[ $OUT == *"Stopped after the first error, 3 files not processed"* ]] || (echo '❌ test: --fail-fast should stop scheduling files' && exit 1)
+[[ ! -e tree/b.txt.enc ]] || (echo '❌ test: No file should be processed after the first error' && exit 1)
+OUT=$(gonc --key-file key -j 1 encrypt tree/*.txt 2>&1 || true)
+[[ -f tree/d.txt.enc && $OUT != *"Stopped"* ]] || (echo '❌ test: Without --fail-fast all files should be processed' && exit 1)
+echo "✅ --fail-fast works"
+
+echo "🧪 Testing interrupts remove temporary files"
+
+mkdir -p big
+for i in 1 2 3 4; do head -c 100M /dev/zero >"big/f$i"; done
+gonc -q -j 1 --key-file key encrypt big 2>/dev/null &
+PID=$!
+sleep 0.3
+kill -TERM $PID
+RC=0
+wait $PID || RC=$?
+[[ $RC -eq 130 ]] || (echo "❌ test: An interrupted run should exit with 130, got $RC" && exit 1)
+[[ -z "$(find big -name '.tmp-*')" ]] || (echo '❌ test: Temporary files should be removed' && exit 1)
+[[ ! -e big/f4.enc ]] || (echo '❌ test: No file should be started after the interrupt' && exit 1)
+echo "✅ Interrupts remove temporary files"
+
+echo "✨ ALL CANCEL TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
@@ -0,0 +1,114 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+mkdir -p keys project/sub
+gogen key >keys/dev
+gogen key -l 64 >keys/prod
+
+cat >project/.gonc.yaml <<'YAML'
+key-file: ../keys/dev
+encrypt-ext: .sealed
+include: ["*.txt"]
+profiles:
+  prod:
+    key-file: ../keys/prod
+    deterministic: true
+YAML
+
+cd project/sub
+echo "a" >a.txt
+echo "b" >b.md
+
+echo "🧪 Testing configuration file defaults"
+
+gonc -q encrypt
+[[ -f a.txt.sealed ]] || (echo '❌ test: Defaults should apply from a parent directory' && exit 1)
+[[ ! -f b.md.sealed ]] || (echo '❌ test: Include patterns should apply from the file' && exit 1)
+gonc -q --delete decrypt a.txt.sealed
+[[ -f a.txt && ! -f a.txt.sealed ]] || (echo '❌ test: Relative paths should resolve next to the file' && exit 1)
+echo "✅ Configuration file defaults work"
+
+echo "🧪 Testing profiles and precedence"
+
+OUT=$(gonc --profile prod --show encrypt)
+echo "${OUT}" | grep -q '"Deterministic": true' || (echo '❌ test: Profile should apply' && exit 1)
+echo "${OUT}" | grep -q '"key-file": "profile \\"prod\\" in' || (echo '❌ test: Show should report the profile as source' && exit 1)
+echo "${OUT}" | grep -q '"encrypt-ext": ".*/project/.gonc.yaml"' || (echo '❌ test: Show should report the file as source' && exit 1)
+
+OUT=$(GONC_ENCRYPT_EXT=.env gonc --profile prod --show encrypt)
+echo "${OUT}" | grep -q '"Encrypt": ".env"' || (echo '❌ test: Env should override the file' && exit 1)
+echo "${OUT}" | grep -q '"encrypt-ext": "env GONC_ENCRYPT_EXT"' || (echo '❌ test: Show should report env as source' && exit 1)
+
+OUT=$(GONC_ENCRYPT_EXT=.env gonc --profile prod --encrypt-ext .flag --show encrypt)
+echo "${OUT}" | grep -q '"Encrypt": ".flag"' || (echo '❌ test: Flags should override env' && exit 1)
+echo "${OUT}" | grep -q '"encrypt-ext": "flag"' || (echo '❌ test: Show should report flags as source' && exit 1)
+
+OUT=$(GONC_PROFILE=prod gonc --show decrypt)
+echo "${OUT}" | grep -q '"File": "../../keys/prod"' || (echo '❌ test: GONC_PROFILE should select the profile' && exit 1)
+echo "${OUT}" | grep -q '"Deterministic": false' || (echo '❌ test: Settings should only apply to commands with that flag' && exit 1)
+echo "✅ Profiles and precedence work"
+
+echo "🧪 Testing invalid configuration"
+
+if gonc --profile missing encrypt 2>/dev/null; then
+  echo "❌ test: Unknown profiles should be rejected" && exit 1
+fi
+echo "bogus: true" >>../.gonc.yaml
+if gonc encrypt 2>/dev/null; then
+  echo "❌ test: Unknown settings should be rejected" && exit 1
+fi
+rm ../.gonc.yaml
+
+cat >../.gonc.jsonc <<'JSON'
+{
+  // JSONC works too
+  "key-file": "../keys/dev",
+  "include": ["*.md"],
+}
+JSON
+gonc -q encrypt
+[[ -f b.md.enc ]] || (echo '❌ test: JSONC configuration files should be read' && exit 1)
+echo "✅ Invalid configuration is rejected and JSONC is read"
+
+echo "🧪 Testing settings that run commands"
+
+for setting in '"key-command": "touch ran && cat ../../keys/dev"' '"key-provider": "exec:touch ran"' '"key-fd": 0'; do
+  echo "{${setting}}" >../.gonc.jsonc
+  if gonc --force decrypt 2>/dev/null; then
+    echo "❌ test: ${setting} should be rejected in a configuration file" && exit 1
+  fi
+  [[ ! -e ran ]] || (echo "❌ test: ${setting} should not run" && exit 1)
+done
+echo '{"insecure-key-file": true}' >../.gonc.jsonc
+if gonc --force decrypt 2>/dev/null; then
+  echo '❌ test: insecure-key-file should be rejected in a configuration file' && exit 1
+fi
+echo '{"delete": true}' >../.gonc.jsonc
+if gonc --force decrypt 2>/dev/null; then
+  echo '❌ test: delete should be rejected in a configuration file' && exit 1
+fi
+[[ -f b.md.enc ]] || (echo '❌ test: delete from a configuration file should not remove files' && exit 1)
+echo '{"key-provider": "local:../../keys/dev"}' >../.gonc.jsonc
+OUT=$(gonc --show decrypt)
+echo "${OUT}" | grep -q '"Provider": "local:../../keys/dev"' || (echo '❌ test: Local providers should be allowed' && exit 1)
+echo "✅ Settings that run commands, weaken checks or delete files are rejected"
+
+echo "✨ ALL CONFIG TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
@@ -0,0 +1,76 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+gogen key >other
+
+echo "a" >a.txt
+echo "b" >b.txt
+
+# expect runs gonc with the remaining arguments and checks its exit code.
+expect() {
+  local want=$1
+  shift
+  local rc=0
+  gonc "$@" >/dev/null 2>&1 || rc=$?
+  [[ $rc -eq $want ]] || (echo "❌ test: gonc $* should exit with $want, got $rc" && exit 1)
+}
+
+echo "🧪 Testing exit codes"
+
+expect 0 --key-file key encrypt a.txt b.txt
+expect 2 --key-file key encrypt --bogus a.txt
+expect 2 --key-file key get a.txt.enc
+expect 2 --key-file key --keep-ext encrypt a.txt
+gogen key -l 16 >short
+expect 2 --key-file short --force encrypt a.txt
+echo "not hex" >nothex
+expect 2 --key-file nothex --force encrypt a.txt
+expect 2 --key-file key --force encrypt ../a.txt
+expect 2 --key-file key --force encrypt "${TMPDIR}/a.txt"
+expect 3 --key-file other --force decrypt a.txt.enc
+echo "garbage" >c.txt.enc
+expect 4 --key-file key --force decrypt c.txt.enc
+head -c 40 a.txt.enc >d.txt.enc
+expect 4 --key-file key --force decrypt d.txt.enc
+{ head -c 5 a.txt.enc; printf '\x02'; tail -c +7 a.txt.enc; } >e.txt.enc
+expect 4 --key-file key --force decrypt e.txt.enc
+expect 5 --key-file key encrypt missing.txt
+expect 6 --key-file key --force decrypt a.txt.enc c.txt.enc
+expect 7 --include 'none' check .
+
+# A plugin that unwraps to a different data key makes every read back fail verification.
+cat >plugin.sh <<'PLUGIN'
+#!/bin/bash
+case $(jq -r .operation) in
+wrap) echo '{"key_id": "kek", "ciphertext": "AA=="}' ;;
+unwrap) echo "{\"plaintext\": \"$(head -c 32 /dev/zero | base64)\"}" ;;
+esac
+PLUGIN
+chmod +x plugin.sh
+echo "v" >v.txt
+expect 5 --key-provider "exec:./plugin.sh" --verify --delete encrypt v.txt
+[[ -f v.txt && ! -e v.txt.enc ]] || (echo '❌ test: A failed verification should keep the input and drop the output' && exit 1)
+OUT=$(gonc --key-provider "exec:./plugin.sh" --verify --output ndjson encrypt v.txt 2>/dev/null || true)
+echo "${OUT}" | tail -1 | grep -q '"verify_failures":1' || (echo '❌ test: The summary should count verification failures' && exit 1)
+echo "✅ Exit codes classify failures"
+
+echo "✨ ALL EXIT CODE TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
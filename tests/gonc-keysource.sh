@@ -0,0 +1,100 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+gogen key -l 64 >key64
+
+echo "a" >a.txt
+
+echo "🧪 Testing --key-command"
+
+gonc -q --key-command "cat key" encrypt a.txt
+gonc -q --key-file key --force decrypt a.txt.enc
+[[ $(cat a.txt) == "a" ]] || (echo '❌ test: --key-command should provide the key' && exit 1)
+
+mkdir -p plain enc
+echo "p" >plain/p.txt
+gonc -q --key-command "echo run >>runs; cat key" sync plain enc
+[[ $(wc -l <runs) -eq 1 ]] || (echo '❌ test: The key command should run once per invocation' && exit 1)
+
+if gonc --key-command "false" --force encrypt a.txt 2>/dev/null; then
+  echo "❌ test: A failing key command should fail the run" && exit 1
+fi
+if gonc --key-command "true" --force encrypt a.txt 2>/dev/null; then
+  echo "❌ test: An empty key command output should fail the run" && exit 1
+fi
+echo "✅ --key-command works"
+
+echo "🧪 Testing --key-fd"
+
+gonc -q --key-fd 3 --force encrypt a.txt 3<key
+gonc -q --key-file key --force decrypt a.txt.enc
+cat key | gonc -q --key-fd 0 --force encrypt a.txt
+gonc -q --key-file key --force decrypt a.txt.enc
+[[ $(cat a.txt) == "a" ]] || (echo '❌ test: --key-fd should provide the key' && exit 1)
+echo "✅ --key-fd works"
+
+echo "🧪 Testing self-describing key files"
+
+printf 'type: gonc-key\nmode: deterministic\nkey: %s\n' "$(cat key64)" >described.yaml
+gonc -q --key-file described.yaml --force encrypt -d a.txt
+gonc -q --key-command "cat described.yaml" --force decrypt a.txt.enc
+printf '{"type": "gonc-key", "mode": "deterministic", "key": "%s"}' "$(cat key)" >wrong.json
+if gonc --key-file wrong.json --force encrypt a.txt 2>/dev/null; then
+  echo "❌ test: A key not fitting its mode should be rejected" && exit 1
+fi
+echo "✅ Self-describing key files work"
+
+echo "🧪 Testing exclusive key sources"
+
+RC=0
+gonc --key-file key --key-command "cat key" --force encrypt a.txt 2>/dev/null || RC=$?
+[[ $RC -eq 2 ]] || (echo "❌ test: Several key sources should be a usage error, got $RC" && exit 1)
+RC=0
+gonc --key-fd 3 -k "$(cat key)" --force encrypt a.txt 3<key 2>/dev/null || RC=$?
+[[ $RC -eq 2 ]] || (echo "❌ test: Several key sources should be a usage error, got $RC" && exit 1)
+echo "✅ Key sources are exclusive"
+
+echo "🧪 Testing key file permissions"
+
+cp key shared
+chmod 640 shared
+RC=0
+gonc --key-file shared --force encrypt a.txt 2>/dev/null || RC=$?
+[[ $RC -eq 2 ]] || (echo "❌ test: Group-readable key files should be refused, got $RC" && exit 1)
+chmod 604 shared
+if gonc --key-provider local:shared --force encrypt a.txt 2>/dev/null; then
+  echo "❌ test: World-readable key-encryption keys should be refused" && exit 1
+fi
+gonc -q --key-file shared --insecure-key-file --force encrypt a.txt
+gonc -q --key-file key --force decrypt a.txt.enc
+[[ $(cat a.txt) == "a" ]] || (echo '❌ test: --insecure-key-file should allow the key file' && exit 1)
+echo "✅ Key file permissions are checked"
+
+echo "🧪 Testing the --key warning"
+
+ERR=$(gonc -q -k "$(cat key)" --force encrypt a.txt 2>&1)
+[[ "${ERR}" == *"Warning: --key is visible"* ]] || (echo '❌ test: --key should print a warning' && exit 1)
+ERR=$(GONC_KEY="$(cat key)" gonc -q --force encrypt a.txt 2>&1)
+[[ -z "${ERR}" ]] || (echo '❌ test: GONC_KEY should not print a warning' && exit 1)
+echo "✅ --key prints a warning"
+
+echo "✨ ALL KEY SOURCE TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
@@ -0,0 +1,67 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+
+mkdir -p work/secrets/nested
+cd work
+echo "one" >secrets/one.txt
+echo "two" >secrets/nested/two.txt
+
+echo "🧪 Testing encrypt --output-dir"
+
+gonc -q --key-file ../key --output-dir ../encrypted encrypt secrets
+[[ -f ../encrypted/secrets/one.txt.enc ]] || (echo '❌ test: Output should mirror the tree' && exit 1)
+[[ -f ../encrypted/secrets/nested/two.txt.enc ]] || (echo '❌ test: Nested directories should be created' && exit 1)
+[[ ! -f secrets/one.txt.enc ]] || (echo '❌ test: Nothing should be written next to the sources' && exit 1)
+echo "✅ Encrypt into output directory works"
+
+echo "🧪 Testing decrypt --output-dir"
+
+cd ../encrypted
+gonc -q --key-file ../key --output-dir ../decrypted decrypt secrets
+[[ "$(cat ../decrypted/secrets/nested/two.txt)" == "two" ]] || (echo '❌ test: Decrypted content mismatch' && exit 1)
+gonc -q --key-file ../key --output-dir ../kept decrypt --keep-ext secrets
+[[ "$(cat ../kept/secrets/one.txt.enc)" == "one" ]] || (echo '❌ test: --keep-ext should keep the encrypt suffix' && exit 1)
+echo "✅ Decrypt into output directory works"
+
+echo "🧪 Testing redact --output-dir"
+
+cd ../work
+gonc -q --output-dir ../redacted redact secrets
+[[ "$(cat ../redacted/secrets/one.txt.enc)" == "<REDACTED>" ]] || (echo '❌ test: Redacted output should be mirrored' && exit 1)
+echo "✅ Redact into output directory works"
+
+echo "🧪 Testing overlap protection"
+
+if gonc -q --key-file ../key --output-dir out encrypt . 2>/dev/null; then
+  echo "❌ test: Output directory inside the source tree should be rejected" && exit 1
+fi
+if gonc -q --key-file ../key --output-dir . encrypt secrets 2>/dev/null; then
+  echo "❌ test: Source tree inside the output directory should be rejected" && exit 1
+fi
+if gonc -q --key-file ../key decrypt --keep-ext secrets 2>/dev/null; then
+  echo "❌ test: --keep-ext without --output-dir should be rejected" && exit 1
+fi
+[[ ! -e out ]] || (echo '❌ test: Nothing should be written when rejected' && exit 1)
+echo "✅ Overlap protection works"
+
+echo "✨ ALL OUTPUT DIR TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
@@ -0,0 +1,62 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+
+mkdir -p tree
+echo "a" >tree/a.txt
+echo "b" >tree/b.txt
+
+echo "🧪 Testing --output ndjson"
+
+OUT=$(gonc --key-file key --output ndjson --delete encrypt tree)
+[[ $(echo "${OUT}" | wc -l) -eq 3 ]] || (echo '❌ test: Expected two file events and a summary' && exit 1)
+echo "${OUT}" | grep -q '"input":"tree/a.txt","output":"tree/a.txt.enc","input_size":2,' || (echo '❌ test: File event mismatch' && exit 1)
+echo "${OUT}" | grep -q '"mode":"randomized"' || (echo '❌ test: Mode should be reported' && exit 1)
+echo "${OUT}" | grep -q '"deleted":true' || (echo '❌ test: Deleted flag should be reported' && exit 1)
+echo "${OUT}" | tail -1 | grep -q '"type":"summary","command":"encrypt","scanned":2,"excluded":0,"processed":2,"errors":0' || (echo '❌ test: Summary mismatch' && exit 1)
+echo "✅ --output ndjson works"
+
+echo "🧪 Testing --output json"
+
+echo "garbage" >tree/c.txt.enc
+OUT=$(gonc --key-file key --output json decrypt tree 2>/dev/null || true)
+echo "${OUT}" | grep -q '"events": \[' || (echo '❌ test: JSON document should hold the events' && exit 1)
+echo "${OUT}" | grep -q '"error_class": "envelope"' || (echo '❌ test: Errors should be classified' && exit 1)
+echo "${OUT}" | grep -q '"errors": 1' || (echo '❌ test: Summary should count errors' && exit 1)
+[[ "${OUT}" != *"Processed"* ]] || (echo '❌ test: No text should be mixed into JSON' && exit 1)
+rm tree/c.txt.enc
+echo "✅ --output json works"
+
+echo "🧪 Testing dry run, redact and check"
+
+OUT=$(gonc --key-file key --output ndjson --dry decrypt tree)
+echo "${OUT}" | grep -q '"dry_run":true' || (echo '❌ test: Dry run events should be marked' && exit 1)
+OUT=$(gonc --output ndjson --force redact tree/a.txt)
+echo "${OUT}" | grep -q '"command":"redact","input":"tree/a.txt"' || (echo '❌ test: Redact should emit events' && exit 1)
+OUT=$(gonc --output ndjson --include '*.txt' --include 'none' check tree 2>/dev/null || true)
+echo "${OUT}" | grep -q '"pattern":"none","matches":0,"error":"matched no files"' || (echo '❌ test: Check should emit pattern events' && exit 1)
+if gonc --output xml check tree 2>/dev/null; then
+  echo "❌ test: Unknown formats should be rejected" && exit 1
+fi
+echo "✅ Dry run, redact and check emit events"
+
+echo "✨ ALL OUTPUT TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
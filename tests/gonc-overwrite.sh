@@ -0,0 +1,71 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+
+echo "🧪 Testing unchanged outputs are replaced"
+
+echo "data" >file.txt
+gonc -q --key-file key encrypt file.txt
+gonc -q --key-file key encrypt file.txt
+echo "✅ Re-encrypting unchanged content works"
+
+echo "🧪 Testing differing outputs are protected"
+
+echo "edited" >file.txt
+if gonc -q --key-file key encrypt file.txt 2>/dev/null; then
+  echo "❌ test: Encrypting over a differing ciphertext should fail" && exit 1
+fi
+echo "local edits" >plain.txt
+cp file.txt.enc plain.txt.enc
+if gonc -q --key-file key decrypt plain.txt.enc 2>/dev/null; then
+  echo "❌ test: Decrypting over local edits should fail" && exit 1
+fi
+[[ "$(cat plain.txt)" == "local edits" ]] || (echo '❌ test: Local edits should be kept' && exit 1)
+echo "✅ Differing outputs are protected"
+
+echo "🧪 Testing --force and --backup"
+
+gonc -q --key-file key --backup decrypt plain.txt.enc
+[[ "$(cat plain.txt.bak)" == "local edits" ]] || (echo '❌ test: Backup should hold the previous output' && exit 1)
+[[ "$(cat plain.txt)" == "data" ]] || (echo '❌ test: Output should be replaced' && exit 1)
+gonc -q --key-file key --force encrypt file.txt
+gonc -q --key-file key --output-dir out decrypt --keep-ext file.txt.enc
+[[ "$(cat out/file.txt.enc)" == "edited" ]] || (echo '❌ test: --force should replace the output' && exit 1)
+if gonc -q --key-file key --force --backup encrypt file.txt 2>/dev/null; then
+  echo "❌ test: --force and --backup should be exclusive" && exit 1
+fi
+echo "✅ --force and --backup work"
+
+echo "🧪 Testing output collisions"
+
+mkdir -p tree
+echo "a" >tree/a
+if gonc -q --key-file key --encrypt-ext "" encrypt tree 2>/dev/null; then
+  echo "❌ test: An output that is also an input should be rejected" && exit 1
+fi
+[[ "$(cat tree/a)" == "a" ]] || (echo '❌ test: Nothing should be written on collisions' && exit 1)
+gonc -q --key-file key --encrypt-ext ".x" encrypt tree/a
+OUT=$(gonc --key-file key --encrypt-ext ".x" --output-dir out2 --dry decrypt tree/a.x tree/a 2>&1 || true)
+[[ $OUT == *"are both written to"* ]] || (echo '❌ test: Two inputs with the same output should be rejected' && exit 1)
+echo "✅ Output collisions are rejected"
+
+echo "✨ ALL OVERWRITE TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
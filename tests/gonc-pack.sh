@@ -0,0 +1,110 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+
+mkdir -p src/a/b
+echo "nested" >src/a/b/file.txt
+echo "#!/bin/sh" >src/run.sh
+chmod 755 src/run.sh
+chmod 755 src/a
+ln -s a/b/file.txt src/link
+touch -d "2020-01-01 00:00:00" src/a/b/file.txt
+
+echo "🧪 Testing pack"
+
+gonc -q --key-file key pack archive.gonc src
+[[ -f archive.gonc ]] || (echo '❌ test: Archive was not created' && exit 1)
+grep -q "nested" archive.gonc && (echo '❌ test: Archive should be encrypted' && exit 1)
+echo "✅ Pack works"
+
+echo "🧪 Testing pack onto an existing archive"
+
+cp archive.gonc previous.gonc
+if gonc -q --key-file key pack archive.gonc src 2>/dev/null; then
+  echo "❌ test: An existing archive should not be replaced without --force" && exit 1
+fi
+cmp -s archive.gonc previous.gonc || (echo '❌ test: The existing archive should be kept' && exit 1)
+gonc -q --key-file key --force pack archive.gonc src
+cmp -s archive.gonc previous.gonc && (echo '❌ test: --force should replace the archive' && exit 1)
+echo "✅ Existing archives are guarded"
+
+echo "🧪 Testing unpack"
+
+gonc -q --key-file key unpack archive.gonc out
+[[ "$(cat out/src/a/b/file.txt)" == "nested" ]] || (echo '❌ test: Content mismatch' && exit 1)
+[[ -x out/src/run.sh ]] || (echo '❌ test: Mode should be preserved' && exit 1)
+[[ -L out/src/link && "$(readlink out/src/link)" == "a/b/file.txt" ]] || (echo '❌ test: Symlink should be preserved' && exit 1)
+[[ "$(stat -c %Y out/src/a/b/file.txt)" == "$(stat -c %Y src/a/b/file.txt)" ]] || (echo '❌ test: Modification time should be preserved' && exit 1)
+[[ "$(stat -c %a out/src/a)" == "755" ]] || (echo '❌ test: Created directories should get their archived mode' && exit 1)
+echo "✅ Unpack works"
+
+echo "🧪 Testing unpack into existing directories"
+
+mkdir -p existing/src
+chmod 750 existing/src
+gonc -q --key-file key unpack archive.gonc existing
+[[ "$(stat -c %a existing/src)" == "750" ]] || (echo '❌ test: Existing directories should keep their mode' && exit 1)
+echo "✅ Existing directories are left alone"
+
+echo "🧪 Testing unpack --include"
+
+gonc -q --key-file key --include "*.sh" unpack archive.gonc partial
+[[ -f partial/src/run.sh ]] || (echo '❌ test: Included file should be extracted' && exit 1)
+[[ ! -e partial/src/a/b/file.txt ]] || (echo '❌ test: Other files should not be extracted' && exit 1)
+echo "✅ Selective unpack works"
+
+echo "🧪 Testing pack with filters"
+
+gonc -q --key-file key --exclude "*.sh" pack filtered.gonc src
+gonc -q --key-file key unpack filtered.gonc filtered
+[[ ! -e filtered/src/run.sh ]] || (echo '❌ test: Excluded file should not be packed' && exit 1)
+echo "✅ Pack filters work"
+
+echo "🧪 Testing path traversal protection"
+
+python3 - <<'PY'
+import io, tarfile
+with tarfile.open("evil.tar", "w") as tar:
+    data = b"evil"
+    info = tarfile.TarInfo("../evil.txt")
+    info.size = len(data)
+    tar.addfile(info, io.BytesIO(data))
+PY
+gonc -q --key-file key encrypt evil.tar
+mkdir -p jail
+if gonc -q --key-file key unpack evil.tar.enc jail 2>/dev/null; then
+  echo "❌ test: Traversal entry should be rejected" && exit 1
+fi
+[[ ! -e evil.txt ]] || (echo '❌ test: Traversal entry was written' && exit 1)
+echo "✅ Path traversal is rejected"
+
+echo "🧪 Testing tampered archive"
+
+cp archive.gonc tampered.gonc
+printf '\x00' | dd of=tampered.gonc bs=1 seek=100 conv=notrunc 2>/dev/null
+if gonc -q --key-file key unpack tampered.gonc tampered 2>/dev/null; then
+  echo "❌ test: Tampered archive should be rejected" && exit 1
+fi
+[[ ! -e tampered/src ]] || (echo '❌ test: Nothing should be extracted from a tampered archive' && exit 1)
+echo "✅ Tampered archive is rejected"
+
+echo "✨ ALL PACK TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
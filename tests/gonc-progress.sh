@@ -0,0 +1,40 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+
+mkdir -p tree
+head -c 1M /dev/zero >tree/a
+head -c 1M /dev/zero >tree/b
+
+echo "🧪 Testing --progress without a terminal"
+
+OUT=$(gonc -q --key-file key --progress encrypt tree 2>&1)
+[[ $OUT == *"Progress: 2.0 MiB / 2.0 MiB (100%) | 2/2 files |"* ]] || (echo '❌ test: Final progress line mismatch' && exit 1)
+OUT=$(gonc -q --key-file key --progress --force decrypt tree 2>&1)
+[[ $OUT == *"| 2/2 files |"* ]] || (echo '❌ test: Decryption should report progress' && exit 1)
+OUT=$(gonc -q --progress --force redact tree/a tree/b 2>&1)
+[[ $OUT == *"Progress: 2.0 MiB / 2.0 MiB (100%) | 2/2 files |"* ]] || (echo '❌ test: Redaction should report progress' && exit 1)
+OUT=$(gonc -q --key-file key --force encrypt tree 2>&1)
+[[ $OUT != *"Progress"* ]] || (echo '❌ test: Progress should be off by default' && exit 1)
+echo "✅ --progress works"
+
+echo "✨ ALL PROGRESS TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
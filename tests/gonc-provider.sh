@@ -0,0 +1,90 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >kek1
+gogen key >kek2
+
+mkdir -p tree
+echo "a" >tree/a.txt
+echo "b" >tree/b.txt
+
+echo "🧪 Testing the local key provider"
+
+gonc -q --key-provider local:kek1 encrypt tree
+head -c 32 tree/a.txt.enc | grep -q "local:" || (echo '❌ test: The provider ID should be stored in the header' && exit 1)
+[[ $(head -c 5 tree/a.txt.enc | tail -c 1 | od -An -tx1 | tr -d ' ') == "02" ]] || (echo '❌ test: Wrapped envelopes should be version 2' && exit 1)
+if gonc --key-provider local:kek2 --force decrypt tree 2>/dev/null; then
+  echo "❌ test: Another key-encryption key should not unwrap the data keys" && exit 1
+fi
+if gonc --key-file kek1 --force decrypt tree 2>/dev/null; then
+  echo "❌ test: Wrapped data keys should require --key-provider" && exit 1
+fi
+gonc -q --key-provider local:kek1 --force decrypt tree
+[[ $(cat tree/a.txt) == "a" ]] || (echo '❌ test: Decryption should restore the content' && exit 1)
+OUT=$(gonc --key-provider local:kek1 --output ndjson --include "*.txt" encrypt tree)
+echo "${OUT}" | grep -q '"unchanged":true' || (echo '❌ test: Unchanged files should be detected with wrapped keys' && exit 1)
+gonc -q --key-provider local:kek1 status tree || (echo '❌ test: Status should compare wrapped envelopes' && exit 1)
+if gonc --key-provider local:kek1 --force --include "*.txt" encrypt -d tree 2>/dev/null; then
+  echo "❌ test: Deterministic mode should be rejected with a provider" && exit 1
+fi
+echo "✅ The local key provider works"
+
+echo "🧪 Testing rewrap"
+
+cp tree/a.txt.enc before.enc
+OUT=$(gonc --key-provider local:kek2 --output ndjson rewrap --from-provider local:kek1 tree)
+echo "${OUT}" | tail -1 | grep -q '"command":"rewrap","scanned":4,"excluded":2,"processed":2,"errors":0' || (echo '❌ test: Rewrap summary mismatch' && exit 1)
+[[ $(tail -c 50 tree/a.txt.enc | od -An -tx1) == $(tail -c 50 before.enc | od -An -tx1) ]] || (echo '❌ test: Rewrap should only change the header' && exit 1)
+if gonc --key-provider local:kek1 --force decrypt tree 2>/dev/null; then
+  echo "❌ test: The old key-encryption key should no longer unwrap" && exit 1
+fi
+OUT=$(gonc --key-provider local:kek2 --output ndjson rewrap --from-provider local:kek1 tree)
+echo "${OUT}" | grep -q '"unchanged":true' || (echo '❌ test: Rewrapped files should be left alone' && exit 1)
+gonc -q --key-provider local:kek2 --force decrypt tree
+[[ $(cat tree/b.txt) == "b" ]] || (echo '❌ test: Rewrapped files should decrypt' && exit 1)
+echo "✅ Rewrap works"
+
+echo "🧪 Testing exec plugins"
+
+cat >plugin.sh <<'PLUGIN'
+#!/bin/bash
+request=$(cat)
+echo "$(jq -r .operation <<<"${request}")" >>calls
+case $(jq -r .operation <<<"${request}") in
+wrap) jq -c '{key_id: "test-kek", ciphertext: .plaintext}' <<<"${request}" ;;
+unwrap) jq -c 'if .key_id == "test-kek" then {plaintext: .ciphertext} else {error: "unknown key"} end' <<<"${request}" ;;
+esac
+PLUGIN
+chmod +x plugin.sh
+
+gonc -q --key-provider "exec:./plugin.sh" --force --include "*.txt" encrypt tree
+[[ $(grep -cx wrap calls) -eq 2 ]] || (echo '❌ test: The plugin should wrap every data key' && exit 1)
+gonc -q --key-provider "exec:./plugin.sh" --force decrypt tree
+[[ $(cat tree/a.txt) == "a" ]] || (echo '❌ test: The plugin should unwrap the data keys' && exit 1)
+gonc -q --key-provider local:kek1 rewrap --from-provider "exec:./plugin.sh" tree
+gonc -q --key-provider local:kek1 --force decrypt tree
+[[ $(cat tree/b.txt) == "b" ]] || (echo '❌ test: Rewrap should move files between providers' && exit 1)
+if gonc --key-provider "exec:false" --force --include "*.txt" encrypt tree 2>/dev/null; then
+  echo "❌ test: A failing plugin should fail the run" && exit 1
+fi
+echo "✅ Exec plugins work"
+
+echo "✨ ALL PROVIDER TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
@@ -0,0 +1,65 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+gogen key -l 64 >key64
+
+mkdir -p tree/sub other
+echo "a" >tree/a.txt
+echo "b" >tree/sub/b.txt
+echo "c" >other/c.txt
+
+echo "🧪 Testing --stats totals"
+
+OUT=$(gonc --key-file key --stats encrypt tree other 2>&1)
+[[ $OUT == *"Input:     6 B"* ]] || (echo '❌ test: Stats should report input bytes' && exit 1)
+[[ $OUT == *"Overhead:  +"* ]] || (echo '❌ test: Stats should report the ciphertext overhead' && exit 1)
+[[ $OUT == *"Speed:"* ]] || (echo '❌ test: Stats should report the throughput' && exit 1)
+[[ $OUT == *"randomized       3 files"* ]] || (echo '❌ test: Stats should break down by mode' && exit 1)
+[[ $OUT == *"tree             2 files"* ]] || (echo '❌ test: Stats should break down by top-level directory' && exit 1)
+[[ $OUT == *"other            1 files"* ]] || (echo '❌ test: Stats should break down by top-level directory' && exit 1)
+[[ $OUT == *"Slowest:"* ]] || (echo '❌ test: Stats should list the slowest files' && exit 1)
+echo "✅ --stats totals work"
+
+echo "🧪 Testing unchanged and skipped counts"
+
+gonc --key-file key64 --force encrypt -d tree/a.txt >/dev/null
+OUT=$(gonc --key-file key64 --stats --force encrypt -d tree/a.txt 2>&1)
+[[ $OUT == *"Unchanged: 1"* ]] || (echo '❌ test: Identical outputs should count as unchanged' && exit 1)
+
+rm -f tree/*.enc tree/sub/*.enc other/*.enc
+for f in tree/a.txt other/c.txt tree/sub/b.txt; do echo "garbage" >$f.enc; done
+OUT=$(gonc --key-file key --stats --fail-fast --parallel 1 decrypt tree/a.txt.enc other/c.txt.enc tree/sub/b.txt.enc 2>&1 || true)
+[[ $OUT == *"Skipped:   2"* ]] || (echo '❌ test: Files not started should count as skipped' && exit 1)
+[[ $OUT == *"Errors (envelope): 1"* ]] || (echo '❌ test: Errors should be counted by class' && exit 1)
+rm tree/*.enc tree/sub/*.enc other/*.enc
+echo "✅ Unchanged and skipped counts work"
+
+echo "🧪 Testing stats in JSON"
+
+OUT=$(gonc --key-file key64 --output ndjson --stats --force encrypt -d tree 2>/dev/null)
+echo "${OUT}" | tail -1 | grep -q '"stats":{"input_bytes":4,' || (echo '❌ test: Summary should carry the stats' && exit 1)
+echo "${OUT}" | tail -1 | grep -q '"modes":{"deterministic":{"files":2,' || (echo '❌ test: JSON stats should break down by mode' && exit 1)
+OUT=$(gonc --key-file key64 --output ndjson --force encrypt -d tree 2>/dev/null)
+[[ "${OUT}" != *'"stats"'* ]] || (echo '❌ test: Stats should only be reported with --stats' && exit 1)
+echo "✅ Stats in JSON work"
+
+echo "✨ ALL STATS TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
@@ -0,0 +1,110 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+KEY=$(cat key)
+
+mkdir -p secrets
+echo "alpha" >secrets/a.txt
+echo "beta" >secrets/b.txt
+echo "gamma" >secrets/c.txt
+echo "orphan" >secrets/o.txt
+
+gonc -q -k "${KEY}" encrypt secrets/a.txt secrets/b.txt secrets/o.txt
+rm -f secrets/o.txt
+
+echo "🧪 Testing status reports up-to-date pairs"
+
+gonc -k "${KEY}" status secrets/a.txt
+echo "✅ Up-to-date pair accepted"
+
+echo "🧪 Testing status detects edited plaintext"
+
+sleep 1
+echo "beta v2" >secrets/b.txt
+if gonc -k "${KEY}" status secrets/b.txt >/dev/null 2>&1; then
+  echo "❌ test: status should fail for edited plaintext" && exit 1
+fi
+OUT=$(gonc -k "${KEY}" status secrets/b.txt 2>/dev/null || true)
+echo "${OUT}" | grep -q "plaintext-newer" || (echo '❌ test: Expected plaintext-newer' && exit 1)
+echo "✅ Edited plaintext detected"
+
+echo "🧪 Testing status detects missing and orphan ciphertexts"
+
+OUT=$(gonc -k "${KEY}" status secrets 2>/dev/null || true)
+echo "${OUT}" | grep -q 'missing-ciphertext *"secrets/c.txt"' || (echo '❌ test: Expected missing-ciphertext' && exit 1)
+echo "${OUT}" | grep -q 'orphan-ciphertext *"secrets/o.txt"' || (echo '❌ test: Expected orphan-ciphertext' && exit 1)
+echo "✅ Missing and orphan ciphertexts detected"
+
+echo "🧪 Testing status --json"
+
+OUT=$(gonc -k "${KEY}" status --json secrets 2>/dev/null || true)
+echo "${OUT}" | grep -q '"state": "missing-ciphertext"' || (echo '❌ test: JSON output mismatch' && exit 1)
+echo "✅ JSON output works"
+
+echo "🧪 Testing status with deterministic mode"
+
+gogen key -l 64 >key64
+KEY64=$(cat key64)
+
+echo "delta" >secrets/d.txt
+gonc -q -k "${KEY64}" encrypt -d secrets/d.txt
+gonc -k "${KEY64}" status secrets/d.txt
+echo "delta v2" >secrets/d.txt
+if gonc -k "${KEY64}" status secrets/d.txt >/dev/null 2>&1; then
+  echo "❌ test: status should fail for changed deterministic plaintext" && exit 1
+fi
+echo "✅ Deterministic comparison works"
+
+echo "🧪 Testing status without a key"
+
+echo "epsilon" >secrets/e.txt
+gonc -q -k "${KEY}" --preserve-timestamps encrypt secrets/e.txt
+gonc status secrets/e.txt >/dev/null || (echo '❌ test: Equal modification times should be up to date' && exit 1)
+touch -d "+1 hour" secrets/e.txt.enc
+OUT=$(gonc status secrets/e.txt 2>/dev/null || true)
+echo "${OUT}" | grep -q "ciphertext-newer" || (echo '❌ test: Expected ciphertext-newer' && exit 1)
+touch -d "+2 hours" secrets/e.txt
+OUT=$(gonc status secrets/e.txt 2>/dev/null || true)
+echo "${OUT}" | grep -q "plaintext-newer" || (echo '❌ test: Expected plaintext-newer' && exit 1)
+echo "✅ Modification times are compared both ways"
+
+echo "🧪 Testing status with different contents and equal modification times"
+
+echo "zeta" >secrets/z.txt
+gonc -q -k "${KEY}" --preserve-timestamps encrypt secrets/z.txt
+echo "zeta v2" >secrets/z.txt
+touch -r secrets/z.txt.enc secrets/z.txt
+OUT=$(gonc -k "${KEY}" status secrets/z.txt 2>/dev/null || true)
+echo "${OUT}" | grep -q "^differs" || (echo '❌ test: Expected differs' && exit 1)
+echo "✅ Differing contents with equal times are reported"
+
+echo "🧪 Testing status with --output-dir"
+
+echo "eta" >secrets/h.txt
+gonc -q -k "${KEY}" --output-dir mirror encrypt secrets/h.txt
+gonc -k "${KEY}" --output-dir mirror status secrets/h.txt >/dev/null ||
+  (echo '❌ test: Pair in the output directory should be up to date' && exit 1)
+OUT=$(gonc -k "${KEY}" --output-dir mirror status secrets/h.txt)
+echo "${OUT}" | grep -q '"mirror/secrets/h.txt.enc"' || (echo '❌ test: Ciphertext should be looked up in the output directory' && exit 1)
+echo "✅ Output directory is honored"
+
+echo "✨ ALL STATUS TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
@@ -0,0 +1,114 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+KEY=$(cat key)
+
+cat >config.yaml <<'INNER'
+# database settings
+db:
+  user: admin # the user
+  password: "s3cr3t"
+  port: 5432
+INNER
+
+echo "🧪 Testing structured encryption keeps keys and comments"
+
+gonc -q -k "${KEY}" encrypt --structured config.yaml
+grep -q "# database settings" config.yaml.enc || (echo '❌ test: Comment was not preserved' && exit 1)
+grep -q 'password: "GONC\[' config.yaml.enc || (echo '❌ test: Value was not encrypted' && exit 1)
+grep -q "s3cr3t" config.yaml.enc && (echo '❌ test: Plaintext value leaked' && exit 1)
+echo "✅ Structured encryption works"
+
+echo "🧪 Testing structured decryption restores values"
+
+mv config.yaml original.yaml
+gonc -q -k "${KEY}" decrypt --structured config.yaml.enc
+[[ "$(gonc get config.yaml db.password)" == "s3cr3t" ]] || (echo '❌ test: Decrypted value mismatch' && exit 1)
+[[ "$(gonc get config.yaml db.port)" == "5432" ]] || (echo '❌ test: Number type was not restored' && exit 1)
+cmp -s config.yaml original.yaml || (echo '❌ test: Round trip changed the file' && exit 1)
+echo "✅ Structured decryption works"
+
+rm -f config.yaml.enc
+
+echo "🧪 Testing --encrypted-regex"
+
+gonc -q -k "${KEY}" encrypt --structured --encrypted-regex 'password$' config.yaml
+grep -q "user: admin # the user" config.yaml.enc || (echo '❌ test: Unselected value should stay plaintext' && exit 1)
+grep -q 'password: "GONC\[' config.yaml.enc || (echo '❌ test: Selected value was not encrypted' && exit 1)
+echo "✅ --encrypted-regex works"
+
+echo "🧪 Testing get and set"
+
+[[ "$(gonc -k "${KEY}" get config.yaml.enc db.password)" == "s3cr3t" ]] || (echo '❌ test: get mismatch' && exit 1)
+cp config.yaml.enc before
+gonc -k "${KEY}" set config.yaml.enc db.password n3w
+[[ "$(gonc -k "${KEY}" get config.yaml.enc db.password)" == "n3w" ]] || (echo '❌ test: set did not update value' && exit 1)
+[[ "$(diff before config.yaml.enc | grep -c '^[<>]')" == "2" ]] || (echo '❌ test: set touched other lines' && exit 1)
+if gonc -k "${KEY}" set config.yaml.enc db.port abc 2>/dev/null; then
+  echo "❌ test: set should reject a non-numeric port" && exit 1
+fi
+echo "✅ get and set work"
+
+echo "🧪 Testing set keeps the mode of the value it replaces"
+
+gogen key -l 64 >key64
+cat >modes.yaml <<'INNER'
+token: abc
+INNER
+gonc -q -k "$(cat key64)" encrypt --structured -d modes.yaml
+gonc -k "$(cat key64)" set modes.yaml.enc token xyz
+cp modes.yaml.enc first
+gonc -k "$(cat key64)" set modes.yaml.enc token xyz
+cmp -s first modes.yaml.enc || (echo '❌ test: set changed a deterministic value to randomized' && exit 1)
+[[ "$(gonc -k "$(cat key64)" get modes.yaml.enc token)" == "xyz" ]] || (echo '❌ test: get mismatch after set' && exit 1)
+echo "✅ set keeps the mode"
+
+echo "🧪 Testing escaped dots in paths"
+
+cat >dotted.json <<'INNER'
+{"hosts": {"example.com": {"port": 80}, "example": {"com": {"port": 81}}}}
+INNER
+[[ "$(gonc get dotted.json 'hosts.example\.com.port')" == "80" ]] || (echo '❌ test: escaped path mismatch' && exit 1)
+[[ "$(gonc get dotted.json hosts.example.com.port)" == "81" ]] || (echo '❌ test: nested path mismatch' && exit 1)
+gonc set dotted.json 'hosts.example\.com.port' 8080
+[[ "$(gonc get dotted.json 'hosts.example\.com.port')" == "8080" ]] || (echo '❌ test: set on escaped path failed' && exit 1)
+[[ "$(gonc get dotted.json hosts.example.com.port)" == "81" ]] || (echo '❌ test: set touched the wrong value' && exit 1)
+echo "✅ Escaped dots work"
+
+echo "🧪 Testing YAML values that also appear in their keys"
+
+cat >echo.yaml <<'INNER'
+user: user
+host80: 80
+m: {a: b, b: b}
+INNER
+cp echo.yaml echo.orig
+gonc -q -k "${KEY}" encrypt --structured echo.yaml
+grep -q '^user: "GONC\[' echo.yaml.enc || (echo '❌ test: Value equal to its key was not encrypted' && exit 1)
+grep -q '^host80: "GONC\[' echo.yaml.enc || (echo '❌ test: Value contained in its key was not encrypted' && exit 1)
+grep -Eq '^m: \{a: "GONC\[[^]]*\]", b: "GONC\[[^]]*\]"\}$' echo.yaml.enc || (echo '❌ test: Flow mapping values were not encrypted' && exit 1)
+rm echo.yaml
+gonc -q -k "${KEY}" decrypt --structured echo.yaml.enc
+cmp -s echo.yaml echo.orig || (echo '❌ test: Round trip changed the file' && exit 1)
+echo "✅ Values are located at their own position"
+
+echo "✨ ALL STRUCTURED TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
@@ -0,0 +1,72 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+
+mkdir -p secrets/nested
+echo "one" >secrets/one.txt
+echo "two" >secrets/nested/two.txt
+
+echo "🧪 Testing initial sync"
+
+gonc -q --key-file key sync secrets secrets.enc
+[[ -f secrets.enc/one.txt.enc && -f secrets.enc/nested/two.txt.enc ]] || (echo '❌ test: Plaintext should be encrypted' && exit 1)
+[[ -f secrets/.gonc-sync.json ]] || (echo '❌ test: Sync state should be recorded' && exit 1)
+[[ ! -f secrets.enc/.gonc-sync.json.enc ]] || (echo '❌ test: Sync state should not be synced' && exit 1)
+OUT=$(gonc --key-file key sync secrets secrets.enc)
+[[ -z $OUT ]] || (echo '❌ test: A second sync should do nothing' && exit 1)
+echo "✅ Initial sync works"
+
+echo "🧪 Testing propagation"
+
+echo "one changed" >secrets/one.txt
+OUT=$(gonc --key-file key --dry sync secrets secrets.enc)
+[[ $OUT == *"encrypt"*"one.txt"* ]] || (echo '❌ test: Dry run should preview the encryption' && exit 1)
+gonc -q --key-file key sync secrets secrets.enc
+mkdir other && gonc -q --key-file key decrypt secrets.enc/one.txt.enc && mv secrets.enc/one.txt other/
+[[ "$(cat other/one.txt)" == "one changed" ]] || (echo '❌ test: Plaintext change should be encrypted' && exit 1)
+
+echo "two remote" >other/two.txt
+gonc -q --key-file key encrypt other/two.txt
+mv other/two.txt.enc secrets.enc/nested/two.txt.enc
+gonc -q --key-file key sync secrets secrets.enc
+[[ "$(cat secrets/nested/two.txt)" == "two remote" ]] || (echo '❌ test: Ciphertext change should be decrypted' && exit 1)
+
+rm secrets/nested/two.txt
+gonc -q --key-file key sync secrets secrets.enc
+[[ ! -f secrets.enc/nested/two.txt.enc ]] || (echo '❌ test: Plaintext deletion should be propagated' && exit 1)
+echo "✅ Propagation works"
+
+echo "🧪 Testing conflicts"
+
+echo "local" >secrets/one.txt
+echo "remote" >other/one.txt
+gonc -q --key-file key encrypt other/one.txt
+cp other/one.txt.enc secrets.enc/one.txt.enc
+if gonc -q --key-file key sync secrets secrets.enc 2>/dev/null; then
+  echo "❌ test: Conflicting changes should fail" && exit 1
+fi
+[[ "$(cat secrets/one.txt)" == "local" ]] || (echo '❌ test: Conflicts should not be touched' && exit 1)
+gonc -q --key-file key sync --prefer encrypted secrets secrets.enc
+[[ "$(cat secrets/one.txt)" == "remote" ]] || (echo '❌ test: --prefer encrypted should decrypt' && exit 1)
+echo "✅ Conflicts work"
+
+echo "✨ ALL SYNC TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
@@ -0,0 +1,99 @@
+#!/bin/bash
+# shellcheck disable=all
+
+# jscpd:ignore-start
+
+set -euo pipefail
+
+trap 'echo "🚨🚨 Tests failed! 🚨🚨"' ERR
+
+go install -buildvcs=false .
+
+# Create and move to temporary directory
+TMPDIR=$(mktemp -d)
+trap 'rm -rf "$TMPDIR"' EXIT
+cd "${TMPDIR}"
+
+# Key files must only be accessible by their owner
+umask 077
+
+gogen key >key
+
+mkdir -p build/bin
+echo "config" >build/app.yaml
+echo "binary" >build/bin/app
+chmod 755 build/bin/app
+ln -s bin/app build/current
+ln build/app.yaml build/app-link.yaml
+head -c 2621440 /dev/urandom >build/large.bin
+tar -cf artifact.tar build
+
+echo "🧪 Testing tar encrypt"
+
+gonc --key-file key tar encrypt <artifact.tar >encrypted.tar
+LISTING=$(tar -tvf encrypted.tar)
+[[ $LISTING == *"build/app.yaml.enc"* ]] || (echo '❌ test: Entry names should get the encrypt suffix' && exit 1)
+[[ $LISTING == *"build/current -> bin/app"* ]] || (echo '❌ test: Symlinks should pass through' && exit 1)
+[[ $LISTING == *"build/bin/"* ]] || (echo '❌ test: Directories should pass through' && exit 1)
+mkdir listed && tar -xf encrypted.tar -C listed
+grep -q "config" listed/build/app.yaml.enc && (echo '❌ test: Entry data should be encrypted' && exit 1)
+echo "✅ Tar encrypt works"
+
+echo "🧪 Testing tar decrypt"
+
+gonc --key-file key tar decrypt <encrypted.tar >decrypted.tar
+mkdir restored && tar -xf decrypted.tar -C restored
+[[ "$(cat restored/build/app.yaml)" == "config" ]] || (echo '❌ test: Content mismatch' && exit 1)
+[[ -x restored/build/bin/app ]] || (echo '❌ test: Mode should be preserved' && exit 1)
+cmp -s restored/build/large.bin build/large.bin || (echo '❌ test: Large entry mismatch' && exit 1)
+[[ "$(cat restored/build/app-link.yaml)" == "config" ]] || (echo '❌ test: Hard link should be restored' && exit 1)
+echo "✅ Tar decrypt works"
+
+echo "🧪 Testing tar hard links"
+
+grep -Eq "yaml\.enc link to build/app(-link)?\.yaml\.enc$" <<<"$(tar -tvf encrypted.tar)" ||
+  (echo '❌ test: Hard links should follow their renamed target' && exit 1)
+echo "✅ Tar hard links work"
+
+echo "🧪 Testing tar with deterministic mode"
+
+gogen key -l 64 >key64
+gonc --key-file key64 tar encrypt -d <artifact.tar >deterministic.tar
+gonc --key-file key64 tar decrypt <deterministic.tar >deterministic-out.tar
+mkdir deterministic && tar -xf deterministic-out.tar -C deterministic
+cmp -s deterministic/build/large.bin build/large.bin || (echo '❌ test: Multi-chunk entry mismatch' && exit 1)
+echo "✅ Tar deterministic mode works"
+
+echo "🧪 Testing tar encrypt with filters"
+
+gonc --key-file key --include "*.yaml" tar encrypt <artifact.tar >partial.tar
+LISTING=$(tar -tf partial.tar)
+[[ $LISTING == *"build/app.yaml.enc"* ]] || (echo '❌ test: Included entry should be encrypted' && exit 1)
+[[ $LISTING == *"build/bin/app"$'\n'* || $LISTING == *"build/bin/app" ]] || (echo '❌ test: Other entries should pass through' && exit 1)
+[[ $LISTING != *"build/bin/app.enc"* ]] || (echo '❌ test: Other entries should not be encrypted' && exit 1)
+echo "✅ Tar filters work"
+
+echo "🧪 Testing tar decrypt with a wrong key"
+
+gogen key >other
+if gonc --key-file other tar decrypt <encrypted.tar >/dev/null 2>&1; then
+  echo "❌ test: Decrypting with a wrong key should fail" && exit 1
+fi
+echo "✅ Wrong key is rejected"
+
+echo "🧪 Testing tar decrypt with a tampered entry"
+
+# The entry data follows its 512-byte header; its last 32 bytes are the authentication tag.
+echo "plaintext-marker" >marker.txt
+tar -cf marker.tar marker.txt
+gonc --key-file key tar encrypt <marker.tar >marker-enc.tar
+printf 'XXXX' | dd of=marker-enc.tar bs=1 seek=$((512 + 7 + 16 + 17 + 28)) conv=notrunc 2>/dev/null
+if gonc --key-file key tar decrypt <marker-enc.tar >tampered.tar 2>/dev/null; then
+  echo "❌ test: Decrypting a tampered entry should fail" && exit 1
+fi
+grep -q "plaintext-marker" tampered.tar && (echo '❌ test: Tampered entry data should not be written' && exit 1)
+echo "✅ Tampered entries are not written"
+
+echo "✨ ALL TAR TESTS PASSED ! ✨"
+
+# jscpd:ignore-end
@@ -21,6 +21,9 @@ TMPDIR=$(mktemp -d)
 trap 'rm -rf "$TMPDIR"' EXIT
 cd "${TMPDIR}"
 
+# Key files must only be accessible by their owner
+umask 077
+
 echo "🧪 Testing with DEFAULT KEY"
 
 # Generate encryption key
@@ -152,6 +155,32 @@ cmp -s test.sh.enc1 test.sh.enc2 || (echo '❌ File content changed' && exit 1)
 cmp -s test.sh.enc1 test.sh.enc3 || (echo '❌ File content changed' && exit 1)
 cmp -s test.sh.enc2 test.sh.enc3 || (echo '❌ File content changed' && exit 1)
 
+echo "🧪 Testing durable writes"
+
+echo "durable" >durable.txt
+gonc -q -k "${KEY}" --delete encrypt durable.txt
+[[ -f durable.txt.enc && ! -e durable.txt ]] || (echo '❌ test: Durable --delete should replace the input' && exit 1)
+gonc -q -k "${KEY}" --durable on --delete decrypt durable.txt.enc
+gonc -q -k "${KEY}" --durable off encrypt durable.txt
+[[ "$(cat durable.txt)" == "durable" ]] || (echo '❌ test: --durable should not change the content' && exit 1)
+if gonc -q -k "${KEY}" --durable always encrypt durable.txt 2>/dev/null; then
+  echo "❌ test: Unknown --durable values should be rejected" && exit 1
+fi
+echo "✅ Durable writes work"
+
+echo "🧪 Testing --verify"
+
+echo "verified" >verify.txt
+OUT=$(gonc -k "${KEY}" --verify --delete --stats encrypt verify.txt 2>&1)
+[[ $OUT == *"Verify:    0 failed"* ]] || (echo '❌ test: --stats should count verification failures' && exit 1)
+[[ -f verify.txt.enc && ! -e verify.txt ]] || (echo '❌ test: A verified input should be deleted' && exit 1)
+gonc -q -k "${KEY}" --verify --delete decrypt verify.txt.enc
+[[ "$(cat verify.txt)" == "verified" ]] || (echo '❌ test: Verified decryption should work' && exit 1)
+if gonc -q -k "${KEY}" --verify encrypt --structured verify.txt 2>/dev/null; then
+  echo "❌ test: --verify should be rejected with --structured" && exit 1
+fi
+echo "✅ --verify works"
+
 echo "✨ ALL TESTS PASSED ! ✨"
 
 # jscpd:ignore-end